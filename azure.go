@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type AzureProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type AzureRepo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Size          int64  `json:"size"` // size in bytes
+	RemoteURL     string `json:"remoteUrl"`
+	WebURL        string `json:"webUrl"`
+	IsFork        bool   `json:"isFork"`
+	IsDisabled    bool   `json:"isDisabled"`
+	DefaultBranch string `json:"defaultBranch"` // refs/heads/<name>
+	Project       struct {
+		Name       string `json:"name"`
+		Visibility string `json:"visibility"` // private or public
+	} `json:"project"`
+}
+
+type AzureMember struct {
+	User struct {
+		PrincipalName string `json:"principalName"`
+		DisplayName   string `json:"displayName"`
+		MailAddress   string `json:"mailAddress"`
+	} `json:"user"`
+}
+
+// Fetch the projects of an organization
+func fetchAzureProjects(token, org string) ([]AzureProject, error) {
+	var projects []AzureProject
+	cont := ""
+	for {
+		u := fmt.Sprintf("https://dev.azure.com/%s/_apis/projects?api-version=7.0&$top=100", url.PathEscape(org))
+		if cont != "" {
+			u += "&continuationToken=" + url.QueryEscape(cont)
+		}
+		body, next, err := azureApiGet(token, u)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Value []AzureProject `json:"value"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		projects = append(projects, page.Value...)
+		if next == "" || len(page.Value) == 0 {
+			return projects, nil
+		}
+		cont = next
+	}
+}
+
+// Fetch the Git repositories of a project
+func fetchAzureRepos(token, org, project string) ([]AzureRepo, error) {
+	u := fmt.Sprintf("https://dev.azure.com/%s/%s/_apis/git/repositories?api-version=7.0", url.PathEscape(org), url.PathEscape(project))
+	body, _, err := azureApiGet(token, u)
+	if err != nil {
+		return nil, err
+	}
+	var page struct {
+		Value []AzureRepo `json:"value"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+	return page.Value, nil
+}
+
+// Fetch the users of an organization (user entitlements)
+func fetchAzureMembers(token, org string) ([]AzureMember, error) {
+	var members []AzureMember
+	cont := ""
+	for {
+		u := fmt.Sprintf("https://vsaex.dev.azure.com/%s/_apis/userentitlements?api-version=7.1-preview.3", url.PathEscape(org))
+		if cont != "" {
+			u += "&continuationToken=" + url.QueryEscape(cont)
+		}
+		body, _, err := azureApiGet(token, u)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Members           []AzureMember `json:"members"`
+			ContinuationToken string        `json:"continuationToken"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		members = append(members, page.Members...)
+		if page.ContinuationToken == "" || len(page.Members) == 0 {
+			return members, nil
+		}
+		cont = page.ContinuationToken
+	}
+}
+
+// Fetch the names of all organizations the token's user belongs to
+// (needs a PAT created for "All accessible organizations")
+func fetchAzureOrganizations(token string) ([]string, error) {
+	body, _, err := azureApiGet(token, "https://app.vssps.visualstudio.com/_apis/profile/profiles/me?api-version=7.0")
+	if err != nil {
+		return nil, err
+	}
+	var profile struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &profile); err != nil {
+		return nil, err
+	}
+	body, _, err = azureApiGet(token, "https://app.vssps.visualstudio.com/_apis/accounts?api-version=7.0&memberId="+url.QueryEscape(profile.ID))
+	if err != nil {
+		return nil, err
+	}
+	var accounts struct {
+		Value []struct {
+			AccountName string `json:"accountName"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &accounts); err != nil {
+		return nil, err
+	}
+	var orgs []string
+	for _, a := range accounts.Value {
+		orgs = append(orgs, a.AccountName)
+	}
+	return orgs, nil
+}
+
+// splitAzureOrg splits an --orgname value of the form org or org/project
+func splitAzureOrg(name string) (org, project string) {
+	org, project, _ = strings.Cut(name, "/")
+	return org, project
+}
+
+// Helper for Azure DevOps REST requests, authenticated with a PAT as basic auth.
+// Returns the body and the x-ms-continuationtoken header used by paged lists.
+func azureApiGet(token, rawURL string) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(":"+token)))
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, body, err := doRequest(req)
+	if err != nil {
+		return nil, "", err
+	}
+	// an invalid or expired PAT gets a 203 with the HTML sign-in page instead of a 401
+	if resp.StatusCode == http.StatusNonAuthoritativeInfo {
+		return nil, "", fmt.Errorf("authentication failed (check the personal access token)")
+	}
+	return body, resp.Header.Get("X-Ms-Continuationtoken"), nil
+}
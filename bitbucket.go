@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type BitbucketRepo struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	FullName string `json:"full_name"` // workspace/slug
+	Size     int64  `json:"size"`      // size in bytes
+	Parent   *struct {
+		FullName string `json:"full_name"`
+	} `json:"parent"` // set for forks
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+		Clone []struct {
+			Name string `json:"name"` // https or ssh
+			Href string `json:"href"`
+		} `json:"clone"`
+	} `json:"links"`
+	Description string `json:"description"`
+	IsPrivate   bool   `json:"is_private"`
+	Language    string `json:"language"`
+	MainBranch  *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedOn time.Time `json:"updated_on"`
+}
+
+type BitbucketMember struct {
+	User struct {
+		Nickname    string `json:"nickname"`
+		DisplayName string `json:"display_name"`
+		UUID        string `json:"uuid"`
+	} `json:"user"`
+}
+
+// Bitbucket wraps every list in a page object and links the next page in the body
+type bitbucketPage struct {
+	Values json.RawMessage `json:"values"`
+	Next   string          `json:"next"`
+}
+
+// Fetch workspace members
+func fetchBitbucketMembers(token, workspace string) ([]BitbucketMember, error) {
+	var members []BitbucketMember
+	next := fmt.Sprintf("https://api.bitbucket.org/2.0/workspaces/%s/members?pagelen=100", url.PathEscape(workspace))
+	for next != "" {
+		page, err := bitbucketApiGet(token, next)
+		if err != nil {
+			return nil, err
+		}
+		var values []BitbucketMember
+		if err := json.Unmarshal(page.Values, &values); err != nil {
+			return nil, err
+		}
+		members = append(members, values...)
+		next = page.Next
+	}
+	return members, nil
+}
+
+// Fetch repositories of a workspace. A user's personal workspace can be addressed by
+// the user's UUID ({...}), which is how member-owned repos are listed.
+func fetchBitbucketRepos(token, workspace string) ([]BitbucketRepo, error) {
+	var repos []BitbucketRepo
+	next := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s?pagelen=100", url.PathEscape(workspace))
+	for next != "" {
+		page, err := bitbucketApiGet(token, next)
+		if err != nil {
+			return nil, err
+		}
+		var values []BitbucketRepo
+		if err := json.Unmarshal(page.Values, &values); err != nil {
+			return nil, err
+		}
+		repos = append(repos, values...)
+		next = page.Next
+	}
+	return repos, nil
+}
+
+// Helper for Bitbucket API requests. A token of the form username:app_password
+// uses app-password (basic) auth, anything else is sent as a bearer access token.
+func bitbucketApiGet(token, rawURL string) (bitbucketPage, error) {
+	var page bitbucketPage
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return page, err
+	}
+	if user, pass, ok := strings.Cut(token, ":"); ok {
+		req.SetBasicAuth(user, pass)
+	} else if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	_, body, err := doRequest(req)
+	if err != nil {
+		return page, err
+	}
+	err = json.Unmarshal(body, &page)
+	return page, err
+}
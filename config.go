@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the layout of the YAML config file. A profile maps long flag
+// names (provider, base-url, format, include-forks, parallel, ...) to values,
+// plus "orgs", a list of org entries in org file syntax. "token" takes a token
+// reference (env:NAME or file:PATH), never the token itself.
+//
+//	profiles:
+//	  estate:
+//	    provider: github
+//	    token: env:GITHUB_TOKEN
+//	    orgs:
+//	      - acme
+//	      - gitlab@https://git.corp:platform token=env:CORP_GITLAB_TOKEN
+//	    format: csv
+//	    download: true
+//	    parallel: 8
+type configFile struct {
+	Profiles map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+// defaultConfigPath returns ~/.config/orgfetch/config.yaml (or the platform equivalent)
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "orgfetch", "config.yaml")
+}
+
+// applyProfile loads the named profile and sets every flag it defines that was
+// not given on the command line, so flags always override the config. Flags are
+// looked up in flagSets in order, so a subcommand can pass its parent's flags too.
+func applyProfile(path, name string, flagSets ...*pflag.FlagSet) error {
+	lookup := func(key string) *pflag.Flag {
+		for _, fs := range flagSets {
+			if f := fs.Lookup(key); f != nil {
+				return f
+			}
+		}
+		return nil
+	}
+	changed := func(key string) bool {
+		f := lookup(key)
+		return f != nil && f.Changed
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %v", err)
+	}
+	var cfg configFile
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("parsing %s: %v", path, err)
+	}
+	settings, ok := cfg.Profiles[name]
+	if !ok {
+		var names []string
+		for n := range cfg.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("profile %q not found in %s (available: %s)", name, path, strings.Join(names, ", "))
+	}
+	for key, value := range settings {
+		if key == "orgs" {
+			entries, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("profile %q: orgs must be a list of org entries", name)
+			}
+			// parsed with the org file syntax once all flags are final
+			profileOrgs = nil
+			for _, e := range entries {
+				profileOrgs = append(profileOrgs, fmt.Sprint(e))
+			}
+			continue
+		}
+		f := lookup(key)
+		if f == nil || key == "profile" || key == "config" {
+			return fmt.Errorf("profile %q: unknown setting %q", name, key)
+		}
+		if f.Changed {
+			continue
+		}
+		if (key == "token" || key == "token-file" || key == "token-cmd") &&
+			(changed("token") || changed("token-file") || changed("token-cmd")) {
+			// any token option on the command line replaces the profile's token
+			continue
+		}
+		if list, ok := value.([]interface{}); ok {
+			if sv, ok := f.Value.(pflag.SliceValue); ok {
+				// one element per list entry, as if the flag had been repeated
+				elems := make([]string, len(list))
+				for i, e := range list {
+					if elems[i], err = configValue(e); err != nil {
+						return fmt.Errorf("profile %q: %s: %v", name, key, err)
+					}
+				}
+				if err := sv.Replace(elems); err != nil {
+					return fmt.Errorf("profile %q: %s: %v", name, key, err)
+				}
+				continue
+			}
+		}
+		s, err := configValue(value)
+		if err != nil {
+			return fmt.Errorf("profile %q: %s: %v", name, key, err)
+		}
+		if key == "token" {
+			if s, err = tokenRef(s); err != nil {
+				return fmt.Errorf("profile %q: token: %v", name, err)
+			}
+			tokenSource = fmt.Sprintf("profile %s (%s)", name, value)
+		}
+		if err := f.Value.Set(s); err != nil {
+			return fmt.Errorf("profile %q: %s: %v", name, key, err)
+		}
+	}
+	return nil
+}
+
+// configValue converts a YAML scalar or list to the string form the flag parses
+func configValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			s, err := configValue(e)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
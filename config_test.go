@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestApplyProfileLists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `profiles:
+  audit:
+    include:
+      - "{api,web}-*"
+      - re:^svc-
+    exclude: "*-archive"
+    role: [admin, member]
+    language: Go,Rust
+    parallel: 4
+`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var include, exclude, roles, languages []string
+	var parallel int
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.StringArrayVar(&include, "include", nil, "")
+	fs.StringArrayVar(&exclude, "exclude", nil, "")
+	fs.StringSliceVar(&roles, "role", nil, "")
+	fs.StringSliceVar(&languages, "language", nil, "")
+	fs.IntVar(&parallel, "parallel", 1, "")
+
+	if err := applyProfile(path, "audit", fs); err != nil {
+		t.Fatal(err)
+	}
+	check := func(name string, got, want interface{}) {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	// a glob with a comma stays one pattern
+	check("include", include, []string{"{api,web}-*", "re:^svc-"})
+	check("exclude", exclude, []string{"*-archive"})
+	check("role", roles, []string{"admin", "member"})
+	check("language", languages, []string{"Go", "Rust"})
+	check("parallel", parallel, 4)
+}
+
+func TestApplyProfileCommandLineWins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("profiles:\n  audit:\n    include: [a, b]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var include []string
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.StringArrayVar(&include, "include", nil, "")
+	if err := fs.Parse([]string{"--include", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyProfile(path, "audit", fs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(include, []string{"c"}) {
+		t.Errorf("include = %q, want [c]", include)
+	}
+}
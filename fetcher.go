@@ -1,13 +1,21 @@
 package main
 
+
+
 import (
 
 	"fmt"
 
+	"io"
+
+	"os"
+
 	"os/exec"
 
 )
 
+
+
 func cloneRepo(url, folder string) error {
 
 	cmd := exec.Command("git", "clone", url)
@@ -26,3 +34,77 @@ func cloneRepo(url, folder string) error {
 
 }
 
+
+
+// downloadRepos clones every queued repo of the plan using a pool of --parallel
+
+// workers, reporting progress to w
+
+func downloadRepos(plan *downloadPlan, w io.Writer) {
+
+	for _, e := range plan.Skipped {
+
+		fmt.Fprintf(w, "Skipped: %s (%s)\n", e.URL, e.Reason)
+
+	}
+
+	_ = os.MkdirAll(plan.Folder, 0755)
+
+	type job struct {
+
+		url string
+
+	}
+
+	jobs := make(chan job, len(plan.Clone))
+
+	results := make(chan string, len(plan.Clone))
+
+	workers := parallel
+
+	if workers < 1 {
+
+		workers = 1
+
+	}
+
+	for i := 0; i < workers; i++ {
+
+		go func() {
+
+			for j := range jobs {
+
+				err := cloneRepo(j.url, plan.Folder)
+
+				if err != nil {
+
+					results <- fmt.Sprintf("Failed: %s (%v)", j.url, err)
+
+				} else {
+
+					results <- fmt.Sprintf("Cloned: %s", j.url)
+
+				}
+
+			}
+
+		}()
+
+	}
+
+	for _, e := range plan.Clone {
+
+		jobs <- job{url: e.gitURL()}
+
+	}
+
+	close(jobs)
+
+	for i := 0; i < len(plan.Clone); i++ {
+
+		fmt.Fprintln(w, <-results)
+
+	}
+
+}
+
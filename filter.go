@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// date filter bounds, parsed from the --*-since/--*-before flags by validateFilters
+var pushedAfter, pushedBefore, createdAfter, updatedAfter time.Time
+
+// visibilities and archivedModes list the accepted --visibility and --archived values
+var (
+	visibilities  = []string{"public", "private", "internal"}
+	archivedModes = []string{"include", "exclude", "only"}
+)
+
+// validateFilters checks the filter flags before anything is fetched
+func validateFilters() error {
+	for _, v := range visibilityFilter {
+		if !contains(visibilities, v) {
+			return fmt.Errorf("invalid --visibility %q (expected %s)", v, strings.Join(visibilities, ", "))
+		}
+	}
+	var err error
+	if includePatterns, err = compilePatterns("--include", includeFlag); err != nil {
+		return err
+	}
+	excludes := excludeFlag
+	if excludeFile != "" {
+		lines, err := readPatternFile(excludeFile)
+		if err != nil {
+			return fmt.Errorf("reading --exclude-file: %v", err)
+		}
+		excludes = append(append([]string(nil), excludes...), lines...)
+	}
+	if excludePatterns, err = compilePatterns("--exclude", excludes); err != nil {
+		return err
+	}
+	for _, role := range roleFilter {
+		if !knownRole(role) {
+			return fmt.Errorf("invalid --role %q (expected admin or member on GitHub, or a GitLab role: guest, reporter, developer, maintainer, owner)", role)
+		}
+	}
+	if !contains(topicMatches, topicMatch) {
+		return fmt.Errorf("invalid --topic-match %q (expected %s)", topicMatch, strings.Join(topicMatches, " or "))
+	}
+	if whereFlag != "" {
+		if whereFilter, err = parseWhere(whereFlag); err != nil {
+			return fmt.Errorf("invalid --where expression: %v", err)
+		}
+	}
+	if !contains(archivedModes, archivedMode) {
+		return fmt.Errorf("invalid --archived %q (expected %s)", archivedMode, strings.Join(archivedModes, ", "))
+	}
+	for _, f := range []struct {
+		flag  string
+		value string
+		t     *time.Time
+	}{
+		{"--pushed-since", pushedSince, &pushedAfter},
+		{"--pushed-before", pushedBeforeFlag, &pushedBefore},
+		{"--created-since", createdSince, &createdAfter},
+		{"--updated-since", updatedSince, &updatedAfter},
+	} {
+		if f.value == "" {
+			continue
+		}
+		t, err := parseDateFlag(f.value, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %v", f.flag, f.value, err)
+		}
+		*f.t = t
+	}
+	return nil
+}
+
+// parseDateFlag accepts an absolute date (2024-01-31 or RFC 3339) or a duration
+// before now: 90d, 12w, 1y, 36h (or any Go duration)
+func parseDateFlag(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n >= 0 {
+		switch s[len(s)-1] {
+		case 'd':
+			return now.AddDate(0, 0, -n), nil
+		case 'w':
+			return now.AddDate(0, 0, -7*n), nil
+		case 'y':
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("expected a date (2006-01-02), RFC 3339 time or duration such as 90d, 12w or 1y")
+}
+
+// dateSkip reports why t falls outside [after, before); a zero bound is open
+func dateSkip(what string, t, after, before time.Time, flag string) string {
+	switch {
+	case after.IsZero() && before.IsZero():
+		return ""
+	case t.IsZero():
+		return "no " + what + " date (" + flag + ")"
+	case !after.IsZero() && t.Before(after):
+		return what + " " + t.Format("2006-01-02") + " (" + flag + ")"
+	case !before.IsZero() && !t.Before(before):
+		return what + " " + t.Format("2006-01-02") + " (" + flag + ")"
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// skipReason reports why a repo is excluded by the active filters, or "" when it is kept.
+// Listings and the download plan both go through it so they always agree.
+func skipReason(r RepoInfo) string {
+	if r.Fork && !includeForks {
+		return "fork (use --include-forks)"
+	}
+	if reason := nameSkip(r); reason != "" {
+		return reason
+	}
+	if len(visibilityFilter) > 0 && !contains(visibilityFilter, r.Visibility) {
+		return r.Visibility + " (--visibility)"
+	}
+	if r.Archived && archivedMode == "exclude" {
+		return "archived (--archived exclude)"
+	}
+	if !r.Archived && archivedMode == "only" {
+		return "not archived (--archived only)"
+	}
+	if reason := dateSkip("pushed", r.Pushed, pushedAfter, pushedBefore, "--pushed-since/--pushed-before"); reason != "" {
+		return reason
+	}
+	if reason := dateSkip("created", r.Created, createdAfter, time.Time{}, "--created-since"); reason != "" {
+		return reason
+	}
+	if reason := dateSkip("updated", r.Updated, updatedAfter, time.Time{}, "--updated-since"); reason != "" {
+		return reason
+	}
+	if len(languageFilter) > 0 && !matchLanguage(r.Language) {
+		if r.Language == "" {
+			return "no primary language (--language)"
+		}
+		return "language " + r.Language + " (--language)"
+	}
+	if len(topicFilter) > 0 && !matchTopics(r.Topics) {
+		if len(r.Topics) == 0 {
+			return "no topics (--topic)"
+		}
+		return "topics " + strings.Join(r.Topics, ", ") + " (--topic)"
+	}
+	if whereFilter != nil && !whereFilter.match(r) {
+		return "not matched by --where"
+	}
+	return ""
+}
+
+// filterRepos returns the repos kept by the active filters
+func filterRepos(repos []RepoInfo) []RepoInfo {
+	var out []RepoInfo
+	for _, r := range repos {
+		if skipReason(r) == "" {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// knownRole reports whether role names a GitHub role or a GitLab access level
+func knownRole(role string) bool {
+	role = strings.TrimSpace(role)
+	if strings.EqualFold(role, "admin") || strings.EqualFold(role, "member") {
+		return true
+	}
+	for _, r := range gitlabRoles {
+		if strings.EqualFold(role, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterMembers returns the members whose role is one of --role (case-insensitive)
+func filterMembers(members []MemberInfo) []MemberInfo {
+	var out []MemberInfo
+	for _, m := range members {
+		for _, role := range roleFilter {
+			if strings.EqualFold(strings.TrimSpace(role), m.Role) {
+				out = append(out, m)
+				break
+			}
+		}
+	}
+	return out
+}
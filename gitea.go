@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// giteaPageLimit is the page size requested from Gitea/Forgejo (the server may cap it lower)
+const giteaPageLimit = 50
+
+type GiteaRepo struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Fork     bool   `json:"fork"`
+	Size     int64  `json:"size"` // size in KB
+	Owner    struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	HTMLURL         string    `json:"html_url"`
+	CloneURL        string    `json:"clone_url"`
+	Description     string    `json:"description"`
+	Private         bool      `json:"private"`
+	Internal        bool      `json:"internal"`
+	Archived        bool      `json:"archived"`
+	DefaultBranch   string    `json:"default_branch"`
+	Language        string    `json:"language"`
+	Topics          []string  `json:"topics"`
+	StarsCount      int       `json:"stars_count"`
+	ForksCount      int       `json:"forks_count"`
+	OpenIssuesCount int       `json:"open_issues_count"`
+	Licenses        []string  `json:"licenses"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type GiteaUser struct {
+	Login string `json:"login"`
+}
+
+// Fetch organization members
+func fetchGiteaMembers(baseURL, token, org string) ([]GiteaUser, error) {
+	var members []GiteaUser
+	path := fmt.Sprintf("/orgs/%s/members", url.PathEscape(org))
+	for page := 1; ; page++ {
+		body, total, err := giteaApiGet(baseURL, token, path, page)
+		if err != nil {
+			return nil, err
+		}
+		var users []GiteaUser
+		if err := json.Unmarshal(body, &users); err != nil {
+			return nil, err
+		}
+		members = append(members, users...)
+		if len(users) == 0 || (total >= 0 && len(members) >= total) {
+			return members, nil
+		}
+	}
+}
+
+// Fetch organization repos
+func fetchGiteaRepos(baseURL, token, org string) ([]GiteaRepo, error) {
+	return fetchGiteaRepoPages(baseURL, token, fmt.Sprintf("/orgs/%s/repos", url.PathEscape(org)))
+}
+
+// Fetch user repos
+func fetchGiteaUserRepos(baseURL, token, username string) ([]GiteaRepo, error) {
+	return fetchGiteaRepoPages(baseURL, token, fmt.Sprintf("/users/%s/repos", url.PathEscape(username)))
+}
+
+func fetchGiteaRepoPages(baseURL, token, path string) ([]GiteaRepo, error) {
+	var repos []GiteaRepo
+	for page := 1; ; page++ {
+		body, total, err := giteaApiGet(baseURL, token, path, page)
+		if err != nil {
+			return nil, err
+		}
+		var batch []GiteaRepo
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		repos = append(repos, batch...)
+		if len(batch) == 0 || (total >= 0 && len(repos) >= total) {
+			return repos, nil
+		}
+	}
+}
+
+// Fetch the language breakdown of a repo, in bytes of code per language
+func fetchGiteaLanguages(baseURL, token, owner, name string) (map[string]int64, error) {
+	body, _, err := giteaApiGet(baseURL, token, fmt.Sprintf("/repos/%s/%s/languages", url.PathEscape(owner), url.PathEscape(name)), 1)
+	if err != nil {
+		return nil, err
+	}
+	var langs map[string]int64
+	err = json.Unmarshal(body, &langs)
+	return langs, err
+}
+
+// Helper for Gitea/Forgejo API requests. Returns the page body and the X-Total-Count
+// header, or -1 when the server does not send it.
+func giteaApiGet(baseURL, token, path string, page int) ([]byte, int, error) {
+	u := fmt.Sprintf("%s/api/v1%s?page=%d&limit=%d", baseURL, path, page, giteaPageLimit)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	resp, body, err := doRequest(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if err != nil {
+		total = -1
+	}
+	return body, total, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// giteaStub serves n repos and n members in pages of the requested limit,
+// capped at maxLimit like a Gitea server with a lower MAX_RESPONSE_ITEMS.
+// It counts the requests it answers.
+func giteaStub(t *testing.T, n, maxLimit int, totalCount bool) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.Header.Get("Authorization"); got != "token secret" {
+			t.Errorf("Authorization = %q, want %q", got, "token secret")
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit > maxLimit {
+			limit = maxLimit
+		}
+		var items []map[string]interface{}
+		for i := (page - 1) * limit; i < page*limit && i < n; i++ {
+			switch r.URL.Path {
+			case "/api/v1/orgs/team/repos":
+				items = append(items, map[string]interface{}{"name": fmt.Sprintf("repo%d", i), "full_name": fmt.Sprintf("team/repo%d", i)})
+			case "/api/v1/orgs/team/members":
+				items = append(items, map[string]interface{}{"login": fmt.Sprintf("user%d", i)})
+			default:
+				http.NotFound(w, r)
+				return
+			}
+		}
+		if items == nil {
+			items = []map[string]interface{}{}
+		}
+		if totalCount {
+			w.Header().Set("X-Total-Count", strconv.Itoa(n))
+		}
+		json.NewEncoder(w).Encode(items)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestGiteaPagination(t *testing.T) {
+	tests := []struct {
+		name         string
+		n, maxLimit  int
+		totalCount   bool
+		wantRequests int
+	}{
+		{"total count", 120, giteaPageLimit, true, 3},
+		{"total count, capped limit", 45, 20, true, 3},
+		{"no total count", 120, giteaPageLimit, false, 4}, // stops at the first empty page
+		{"no total count, capped limit", 45, 20, false, 4},
+		{"empty", 0, giteaPageLimit, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := giteaStub(t, tt.n, tt.maxLimit, tt.totalCount)
+
+			repos, err := fetchGiteaRepos(srv.URL, "secret", "team")
+			if err != nil {
+				t.Fatalf("fetchGiteaRepos: %v", err)
+			}
+			if len(repos) != tt.n {
+				t.Errorf("fetchGiteaRepos returned %d repos, want %d", len(repos), tt.n)
+			}
+			for i, r := range repos {
+				if want := fmt.Sprintf("repo%d", i); r.Name != want {
+					t.Errorf("repo %d = %q, want %q", i, r.Name, want)
+					break
+				}
+			}
+			if *requests != tt.wantRequests {
+				t.Errorf("fetchGiteaRepos sent %d requests, want %d", *requests, tt.wantRequests)
+			}
+
+			*requests = 0
+			members, err := fetchGiteaMembers(srv.URL, "secret", "team")
+			if err != nil {
+				t.Fatalf("fetchGiteaMembers: %v", err)
+			}
+			if len(members) != tt.n {
+				t.Errorf("fetchGiteaMembers returned %d members, want %d", len(members), tt.n)
+			}
+			if *requests != tt.wantRequests {
+				t.Errorf("fetchGiteaMembers sent %d requests, want %d", *requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestGiteaError(t *testing.T) {
+	srv, _ := giteaStub(t, 1, giteaPageLimit, true)
+	if _, err := fetchGiteaRepoPages(srv.URL, "secret", "/orgs/missing/repos"); err == nil {
+		t.Error("expected an error for a 404 response")
+	}
+}
@@ -6,12 +6,12 @@ import (
 
 	"fmt"
 
-	"io/ioutil"
-
 	"net/http"
 
 	"strings"
 
+	"time"
+
 )
 
 type Repo struct {
@@ -28,6 +28,46 @@ type Repo struct {
 
 	} `json:"owner"`
 
+	HTMLURL         string    `json:"html_url"`
+
+	CloneURL        string    `json:"clone_url"`
+
+	Description     string    `json:"description"`
+
+	Private         bool      `json:"private"`
+
+	Visibility      string    `json:"visibility"` // public, private or internal
+
+	Archived        bool      `json:"archived"`
+
+	Disabled        bool      `json:"disabled"`
+
+	DefaultBranch   string    `json:"default_branch"`
+
+	Language        string    `json:"language"`
+
+	Topics          []string  `json:"topics"`
+
+	StargazersCount int       `json:"stargazers_count"`
+
+	ForksCount      int       `json:"forks_count"`
+
+	OpenIssuesCount int       `json:"open_issues_count"`
+
+	License         *struct {
+
+		SPDXID string `json:"spdx_id"`
+
+		Name   string `json:"name"`
+
+	} `json:"license"`
+
+	PushedAt  time.Time `json:"pushed_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+
 }
 
 
@@ -36,13 +76,61 @@ type Member struct {
 
 	Login string `json:"login"`
 
+	Role  string `json:"-"` // admin or member, from the role= query that listed it
+
+}
+
+
+
+// fetchMembers lists the org members with their role: owners are listed by
+
+// role=admin, everyone else is a plain member. filter is all, or 2fa_disabled
+
+// for members without two-factor authentication (visible to org owners only).
+
+func fetchMembers(apiBase, token, org, filter string) ([]Member, error) {
+
+	admins, err := fetchMembersByRole(apiBase, token, org, "admin", "all")
+
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	isAdmin := map[string]bool{}
+
+	for _, m := range admins {
+
+		isAdmin[m.Login] = true
+
+	}
+
+	members, err := fetchMembersByRole(apiBase, token, org, "all", filter)
+
+	for i := range members {
+
+		members[i].Role = "member"
+
+		if isAdmin[members[i].Login] {
+
+			members[i].Role = "admin"
+
+		}
+
+	}
+
+	return members, err
+
 }
 
-func fetchMembers(token, org string) ([]Member, error) {
+
+
+func fetchMembersByRole(apiBase, token, org, role, filter string) ([]Member, error) {
 
 	var members []Member
 
-	url := fmt.Sprintf("https://api.github.com/orgs/%s/members", org)
+	url := fmt.Sprintf("%s/orgs/%s/members?per_page=100&role=%s&filter=%s", apiBase, org, role, filter)
 
 	for url != "" {
 
@@ -74,11 +162,11 @@ func fetchMembers(token, org string) ([]Member, error) {
 
 
 
-func fetchRepos(token, org string) ([]Repo, error) {
+func fetchRepos(apiBase, token, org string) ([]Repo, error) {
 
 	var repos []Repo
 
-	url := fmt.Sprintf("https://api.github.com/orgs/%s/repos?per_page=100", org)
+	url := fmt.Sprintf("%s/orgs/%s/repos?per_page=100", apiBase, org)
 
 	for url != "" {
 
@@ -108,11 +196,11 @@ func fetchRepos(token, org string) ([]Repo, error) {
 
 }
 
-func fetchUserRepos(token, username string) ([]Repo, error) {
+func fetchUserRepos(apiBase, token, username string) ([]Repo, error) {
 
 	var repos []Repo
 
-	url := fmt.Sprintf("https://api.github.com/users/%s/repos?per_page=100", username)
+	url := fmt.Sprintf("%s/users/%s/repos?per_page=100", apiBase, username)
 
 	for url != "" {
 
@@ -142,6 +230,28 @@ func fetchUserRepos(token, username string) ([]Repo, error) {
 
 }
 
+
+
+// Fetch the language breakdown of a repo, in bytes of code per language
+
+func fetchRepoLanguages(apiBase, token, owner, name string) (map[string]int64, error) {
+
+	resp, err := apiGet(token, fmt.Sprintf("%s/repos/%s/%s/languages", apiBase, owner, name))
+
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	var langs map[string]int64
+
+	err = json.Unmarshal(resp.Body, &langs)
+
+	return langs, err
+
+}
+
 // Helper for GitHub API requests with pagination
 
 type apiResponse struct {
@@ -156,8 +266,6 @@ type apiResponse struct {
 
 func apiGet(token, url string) (apiResponse, error) {
 
-	client := &http.Client{}
-
 	req, err := http.NewRequest("GET", url, nil)
 
 	if err != nil {
@@ -166,21 +274,39 @@ func apiGet(token, url string) (apiResponse, error) {
 
 	}
 
-	req.Header.Set("Authorization", "token "+token)
+	auth := func(t string) string { return "token " + t }
+
+	pool := tokenPoolFor(token)
 
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
-	resp, err := client.Do(req)
+	// pick the token on every attempt, so a rate-limited request moves on to
 
-	if err != nil {
+	// another token of the pool instead of waiting for this one to reset
 
-		return apiResponse{}, err
+	resp, body, err := doRequestAuth(req, func(req *http.Request) error {
 
-	}
+		tok := pool.pick(req.URL.Host, auth)
+
+		if strings.HasPrefix(tok, appTokenPrefix) {
+
+			// authenticated as a GitHub App installation
+
+			var err error
+
+			if tok, err = appToken(tok); err != nil {
+
+				return err
+
+			}
+
+		}
+
+		req.Header.Set("Authorization", auth(tok))
 
-	defer resp.Body.Close()
+		return nil
 
-	body, err := ioutil.ReadAll(resp.Body)
+	})
 
 	if err != nil {
 
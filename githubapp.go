@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// appTokenPrefix marks a GitHub target's token as a reference to an App
+// installation; apiGet swaps it for a current installation access token.
+// The rest of the reference is the installation's API URL.
+const appTokenPrefix = "github-app:"
+
+// appTokenRefresh is how long before expiry an installation token is replaced
+const appTokenRefresh = 5 * time.Minute
+
+type appInstallationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var (
+	appKey      *rsa.PrivateKey
+	appTokensMu sync.Mutex
+	appTokens   = map[string]appInstallationToken{} // installation URL -> access token
+)
+
+// loadAppKey reads the App's PEM private key (PKCS#1 as downloaded from GitHub, or PKCS#8)
+func loadAppKey(path string) (*rsa.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an RSA private key", path)
+	}
+	return rsaKey, nil
+}
+
+// appJWT signs a short-lived RS256 JWT identifying the App
+func appJWT() (string, error) {
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(), // allow for clock drift
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, appKey, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// appRequest sends a request authenticated as the App itself
+func appRequest(method, rawURL string) ([]byte, error) {
+	jwt, err := appJWT()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	_, body, err := doRequest(req)
+	return body, err
+}
+
+// applyGitHubApp points every GitHub target without its own token= reference at
+// the App's installation for that org (or --app-installation-id)
+func applyGitHubApp(orgs []orgTarget) error {
+	if appID == "" {
+		return nil
+	}
+	if appKeyPath == "" {
+		return fmt.Errorf("--app-id needs --app-key (the App's PEM private key)")
+	}
+	key, err := loadAppKey(appKeyPath)
+	if err != nil {
+		return fmt.Errorf("reading --app-key: %v", err)
+	}
+	appKey = key
+	installations := map[string]string{} // apiBase/org -> installation id
+	for i := range orgs {
+		t := &orgs[i]
+		if t.Provider != "github" || strings.HasPrefix(t.TokenSource, "token=") {
+			continue
+		}
+		id := appInstallationID
+		if id == "" && t.Name == "" {
+			// whoami without an org: there is no installation to look up
+			continue
+		}
+		if id == "" {
+			k := t.apiBase() + "/" + t.Name
+			if id = installations[k]; id == "" {
+				body, err := appRequest("GET", fmt.Sprintf("%s/orgs/%s/installation", t.apiBase(), url.PathEscape(t.Name)))
+				if err != nil {
+					return fmt.Errorf("%s: looking up the GitHub App installation: %v", t.Name, err)
+				}
+				var inst struct {
+					ID int64 `json:"id"`
+				}
+				if err := json.Unmarshal(body, &inst); err != nil {
+					return err
+				}
+				id = fmt.Sprint(inst.ID)
+				installations[k] = id
+			}
+		}
+		t.Token = appTokenPrefix + t.apiBase() + "/app/installations/" + id
+		t.TokenSource = fmt.Sprintf("GitHub App %s (installation %s)", appID, id)
+	}
+	return nil
+}
+
+// appToken returns a valid installation access token for an appTokenPrefix
+// reference, minting a new one when the cached token is about to expire
+func appToken(ref string) (string, error) {
+	installation := strings.TrimPrefix(ref, appTokenPrefix)
+	appTokensMu.Lock()
+	defer appTokensMu.Unlock()
+	if t, ok := appTokens[installation]; ok && time.Until(t.ExpiresAt) > appTokenRefresh {
+		return t.Token, nil
+	}
+	body, err := appRequest("POST", installation+"/access_tokens")
+	if err != nil {
+		return "", fmt.Errorf("creating GitHub App installation token: %v", err)
+	}
+	var t appInstallationToken
+	if err := json.Unmarshal(body, &t); err != nil {
+		return "", err
+	}
+	appTokens[installation] = t
+	return t.Token, nil
+}
@@ -3,81 +3,228 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 type GitLabRepo struct {
-	Name  string `json:"name"`
-	Fork  bool   `json:"fork"`
-	Size  int    `json:"repository_size"` // size in bytes
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Fork       bool   `json:"fork"`
+	Statistics struct {
+		RepositorySize int64 `json:"repository_size"` // size in bytes
+	} `json:"statistics"`
 	Owner struct {
 		Username string `json:"username"`
 	} `json:"owner"`
+	Namespace struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+	WebURL          string    `json:"web_url"`
+	HTTPURLToRepo   string    `json:"http_url_to_repo"`
+	Description     string    `json:"description"`
+	Visibility      string    `json:"visibility"` // public, internal or private
+	Archived        bool      `json:"archived"`
+	DefaultBranch   string    `json:"default_branch"`
+	Topics          []string  `json:"topics"`
+	StarCount       int       `json:"star_count"`
+	ForksCount      int       `json:"forks_count"`
+	OpenIssuesCount int       `json:"open_issues_count"`
+	LastActivityAt  time.Time `json:"last_activity_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 type GitLabMember struct {
-	Username string `json:"username"`
+	Username    string `json:"username"`
+	AccessLevel int    `json:"access_level"`
+	ExpiresAt   string `json:"expires_at"` // 2006-01-02, empty when the membership does not expire
 }
 
-// Fetch group members (users)
-func fetchGitLabMembers(token, group string) ([]GitLabMember, error) {
-	var members []GitLabMember
-	url := fmt.Sprintf("https://gitlab.com/api/v4/groups/%s/members", group)
-	resp, err := gitlabApiGet(token, url)
+// gitlabRoles maps GitLab access levels to their role names
+var gitlabRoles = map[int]string{
+	5:  "Minimal Access",
+	10: "Guest",
+	15: "Planner",
+	20: "Reporter",
+	30: "Developer",
+	40: "Maintainer",
+	50: "Owner",
+}
+
+// info converts the membership to the provider-neutral member
+func (m GitLabMember) info() MemberInfo {
+	return MemberInfo{Login: m.Username, Role: m.role(), Expires: m.ExpiresAt, level: m.AccessLevel}
+}
+
+// role returns the member's role name, or the raw access level when it is not a known one
+func (m GitLabMember) role() string {
+	if r, ok := gitlabRoles[m.AccessLevel]; ok {
+		return r
+	}
+	return fmt.Sprintf("access level %d", m.AccessLevel)
+}
+
+type GitLabGroup struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	FullPath string `json:"full_path"`
+	ParentID int    `json:"parent_id"`
+}
+
+// Fetch a single group by full path (e.g. acme/platform/infra) or numeric ID
+func fetchGitLabGroup(apiBase, token, group string) (GitLabGroup, error) {
+	var g GitLabGroup
+	resp, err := gitlabApiGet(token, fmt.Sprintf("%s/groups/%s?with_projects=false", apiBase, url.PathEscape(group)))
 	if err != nil {
-		return nil, err
+		return g, err
 	}
-	if err := json.Unmarshal(resp, &members); err != nil {
-		return nil, err
+	err = json.Unmarshal(resp.Body, &g)
+	return g, err
+}
+
+// Fetch group members (users)
+func fetchGitLabMembers(apiBase, token, group string) ([]GitLabMember, error) {
+	var members []GitLabMember
+	next := fmt.Sprintf("%s/groups/%s/members?per_page=100", apiBase, url.PathEscape(group))
+	for next != "" {
+		resp, err := gitlabApiGet(token, next)
+		if err != nil {
+			return nil, err
+		}
+		var page []GitLabMember
+		if err := json.Unmarshal(resp.Body, &page); err != nil {
+			return nil, err
+		}
+		members = append(members, page...)
+		next = resp.Next
 	}
 	return members, nil
 }
 
-// Fetch group projects (repos)
-func fetchGitLabRepos(token, group string) ([]GitLabRepo, error) {
+// Fetch group projects (repos), including projects of all descendant groups when recursive is set
+func fetchGitLabRepos(apiBase, token, group string, recursive bool, activeAfter, activeBefore time.Time) ([]GitLabRepo, error) {
 	var repos []GitLabRepo
-	url := fmt.Sprintf("https://gitlab.com/api/v4/groups/%s/projects?per_page=100", group)
-	resp, err := gitlabApiGet(token, url)
-	if err != nil {
-		return nil, err
+	next := fmt.Sprintf("%s/groups/%s/projects?per_page=100&statistics=true", apiBase, url.PathEscape(group))
+	if recursive {
+		next += "&include_subgroups=true"
 	}
-	if err := json.Unmarshal(resp, &repos); err != nil {
-		return nil, err
+	next += activityParams(activeAfter, activeBefore)
+	for next != "" {
+		resp, err := gitlabApiGet(token, next)
+		if err != nil {
+			return nil, err
+		}
+		var page []GitLabRepo
+		if err := json.Unmarshal(resp.Body, &page); err != nil {
+			return nil, err
+		}
+		repos = append(repos, page...)
+		next = resp.Next
 	}
 	return repos, nil
 }
 
-// Fetch user projects (repos)
-func fetchGitLabUserRepos(token, username string) ([]GitLabRepo, error) {
-	var repos []GitLabRepo
-	url := fmt.Sprintf("https://gitlab.com/api/v4/users/%s/projects?per_page=100", username)
-	resp, err := gitlabApiGet(token, url)
+// Fetch all descendant groups (subgroups at any depth)
+func fetchGitLabDescendantGroups(apiBase, token, group string) ([]GitLabGroup, error) {
+	var groups []GitLabGroup
+	next := fmt.Sprintf("%s/groups/%s/descendant_groups?per_page=100", apiBase, url.PathEscape(group))
+	for next != "" {
+		resp, err := gitlabApiGet(token, next)
+		if err != nil {
+			return nil, err
+		}
+		var page []GitLabGroup
+		if err := json.Unmarshal(resp.Body, &page); err != nil {
+			return nil, err
+		}
+		groups = append(groups, page...)
+		next = resp.Next
+	}
+	return groups, nil
+}
+
+// Fetch whether a user has two-factor authentication enabled. GitLab only
+// reports it to administrators, so nil means the token cannot see it.
+func fetchGitLabTwoFactor(apiBase, token, username string) (*bool, error) {
+	resp, err := gitlabApiGet(token, fmt.Sprintf("%s/users?username=%s", apiBase, url.QueryEscape(username)))
 	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(resp, &repos); err != nil {
+	var users []struct {
+		TwoFactorEnabled *bool `json:"two_factor_enabled"`
+	}
+	if err := json.Unmarshal(resp.Body, &users); err != nil {
 		return nil, err
 	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("user %q not found", username)
+	}
+	return users[0].TwoFactorEnabled, nil
+}
+
+// Fetch user projects (repos)
+func fetchGitLabUserRepos(apiBase, token, username string, activeAfter, activeBefore time.Time) ([]GitLabRepo, error) {
+	var repos []GitLabRepo
+	next := fmt.Sprintf("%s/users/%s/projects?per_page=100&statistics=true", apiBase, url.PathEscape(username))
+	next += activityParams(activeAfter, activeBefore)
+	for next != "" {
+		resp, err := gitlabApiGet(token, next)
+		if err != nil {
+			return nil, err
+		}
+		var page []GitLabRepo
+		if err := json.Unmarshal(resp.Body, &page); err != nil {
+			return nil, err
+		}
+		repos = append(repos, page...)
+		next = resp.Next
+	}
 	return repos, nil
 }
 
-// Helper for GitLab API requests
-func gitlabApiGet(token, url string) ([]byte, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
+// activityParams narrows a project list to a last-activity window on the server.
+// Instances that do not support the parameters ignore them, and the same window
+// is applied again on the client.
+func activityParams(after, before time.Time) string {
+	var q string
+	if !after.IsZero() {
+		q += "&last_activity_after=" + url.QueryEscape(after.UTC().Format(time.RFC3339))
+	}
+	if !before.IsZero() {
+		q += "&last_activity_before=" + url.QueryEscape(before.UTC().Format(time.RFC3339))
+	}
+	return q
+}
+
+// Fetch the language breakdown of a project, in percent per language
+func fetchGitLabLanguages(apiBase, token, projectID string) (map[string]float64, error) {
+	resp, err := gitlabApiGet(token, fmt.Sprintf("%s/projects/%s/languages", apiBase, projectID))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("PRIVATE-TOKEN", token)
-	resp, err := client.Do(req)
+	var langs map[string]float64
+	err = json.Unmarshal(resp.Body, &langs)
+	return langs, err
+}
+
+// Helper for GitLab API requests with pagination (GitLab sends the same Link header as GitHub)
+func gitlabApiGet(token, rawURL string) (apiResponse, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
 	if err != nil {
-		return nil, err
+		return apiResponse{}, err
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	req.Header.Set("PRIVATE-TOKEN", token)
+	resp, body, err := doRequest(req)
 	if err != nil {
-		return nil, err
+		return apiResponse{}, err
+	}
+	next := ""
+	for _, part := range splitLinks(resp.Header.Get("Link")) {
+		if part.Rel == "next" {
+			next = part.URL
+		}
 	}
-	return body, nil
+	return apiResponse{Body: body, Next: next}, nil
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LanguageShare is one language of a repo or org breakdown. GitLab only reports
+// percentages, so Bytes is 0 for GitLab repos.
+type LanguageShare struct {
+	Name    string  `json:"name"`
+	Bytes   int64   `json:"bytes,omitempty"`
+	Percent float64 `json:"percent"`
+}
+
+// wantLanguages reports whether the per-repo breakdown must be fetched for t:
+// always with --languages-detail or a --where on languages, and for GitLab
+// with --language or a --where on language, because GitLab project lists
+// carry no primary language
+func wantLanguages(t orgTarget) bool {
+	if !languagesSupported(t.Provider) {
+		return false
+	}
+	if languagesDetail || whereFilter.uses("languages") {
+		return true
+	}
+	return t.Provider == "gitlab" && (len(languageFilter) > 0 || whereFilter.uses("language"))
+}
+
+// languagesSupported reports whether the provider has a per-repo languages endpoint
+func languagesSupported(provider string) bool {
+	return provider == "github" || provider == "gitlab" || provider == "gitea"
+}
+
+// enrichLanguages fetches the language breakdown of every repo that is not an
+// excluded fork and sets the primary language where the provider left it empty.
+// Failures are reported on stderr and leave the repo without a breakdown.
+func enrichLanguages(t orgTarget, repos []RepoInfo) {
+	forEach(len(repos), func(i int) {
+		r := &repos[i]
+		if r.Fork && !includeForks {
+			return
+		}
+		shares, err := fetchLanguages(t, *r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: languages of %s/%s: %v\n", r.Owner, r.Name, err)
+			return
+		}
+		r.Languages = shares
+		if r.Language == "" && len(shares) > 0 {
+			r.Language = shares[0].Name
+		}
+	})
+}
+
+func sharesFromBytes(langs map[string]int64) []LanguageShare {
+	var total int64
+	for _, b := range langs {
+		total += b
+	}
+	var shares []LanguageShare
+	for name, b := range langs {
+		s := LanguageShare{Name: name, Bytes: b}
+		if total > 0 {
+			s.Percent = float64(b) * 100 / float64(total)
+		}
+		shares = append(shares, s)
+	}
+	sortShares(shares)
+	return shares
+}
+
+func sharesFromPercent(langs map[string]float64) []LanguageShare {
+	var shares []LanguageShare
+	for name, p := range langs {
+		shares = append(shares, LanguageShare{Name: name, Percent: p})
+	}
+	sortShares(shares)
+	return shares
+}
+
+// sortShares orders a breakdown by share, largest first
+func sortShares(shares []LanguageShare) {
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Percent != shares[j].Percent {
+			return shares[i].Percent > shares[j].Percent
+		}
+		return shares[i].Name < shares[j].Name
+	})
+}
+
+// aggregateLanguages sums the breakdowns of repos into an org total. Repos
+// without byte counts (GitLab) are weighted by their repository size.
+func aggregateLanguages(repos []RepoInfo) []LanguageShare {
+	bytes := map[string]int64{}
+	for _, r := range repos {
+		for _, s := range r.Languages {
+			if s.Bytes > 0 {
+				bytes[s.Name] += s.Bytes
+			} else {
+				bytes[s.Name] += int64(s.Percent / 100 * float64(r.Size))
+			}
+		}
+	}
+	if len(bytes) == 0 {
+		return nil
+	}
+	return sharesFromBytes(bytes)
+}
+
+// formatLanguages renders a breakdown as "Go 90.0% (9000 B), Shell 10.0% (1000 B)"
+func formatLanguages(shares []LanguageShare) string {
+	var parts []string
+	for _, s := range shares {
+		part := fmt.Sprintf("%s %.1f%%", s.Name, s.Percent)
+		if s.Bytes > 0 {
+			part += fmt.Sprintf(" (%s)", formatBytes(s.Bytes))
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// csvLanguages renders a breakdown as "Go:90.0;Shell:10.0" for a single CSV cell
+func csvLanguages(shares []LanguageShare) string {
+	var parts []string
+	for _, s := range shares {
+		parts = append(parts, s.Name+":"+strconv.FormatFloat(s.Percent, 'f', 1, 64))
+	}
+	return strings.Join(parts, ";")
+}
+
+// matchLanguage reports whether lang is one of the --language values (case-insensitive)
+func matchLanguage(lang string) bool {
+	for _, want := range languageFilter {
+		if strings.EqualFold(strings.TrimSpace(want), lang) {
+			return true
+		}
+	}
+	return false
+}
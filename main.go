@@ -10,8 +10,12 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"io"
+
 	"os"
 
+	"strings"
+
 )
 
 
@@ -54,1485 +58,1043 @@ var (
 
 	parallel      int // new flag for parallel cloning
 
-)
-
-
-
-func main() {
-
-	var rootCmd = &cobra.Command{
-
-		Use:   "github-org-tool",
-
-		Short: "Fetch and report organization/group members and repositories from GitHub or GitLab.",
-
-		Long: `GitHub/GitLab Organization Automation Tool
-
------------------------------------
-
-Fetch and report organization/group members and repositories from GitHub or GitLab with advanced filtering and output options.
-
-Supports organization-wide and member-owned repositories, colored console output, and download link generation.
-
-
-
-Main Features:
-
-  - Fetch organization/group members and repositories (GitHub or GitLab)
-
-  - Filter by member, repo type, and fork status
-
-  - Print colored output and repo URLs
-
-  - Download repositories (with size limit)
-
-  - Output results to file
-
-  - Read multiple orgs/groups from a file (pass filename to --orgname)
-
-  - Print only repo URLs (--urls-only) or only usernames (--usernames-only)
-
-  - Flexible repo type selection: org, member, both
-
-  - All flags have short forms for usability
-
-  - Select provider: --provider github|gitlab
-
-`,
-
-		Example: `
-
-  # Fetch all GitHub org repos (default, forks excluded)
-
-  github-org-tool --provider github --token <TOKEN> --orgname <ORG>
-
-
-
-  # Fetch all GitLab group repos
-
-  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP>
-
-
-
-  # Fetch all org/group repos, including forks
-
-  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --include-forks
-
-  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --include-forks
-
-
-
-  # Fetch member-owned repos for all members/users
-
-  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --repo-type member
-
-  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --repo-type member
-
-
-
-  # Print only repo URLs
-
-  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --urls-only
+	dryRun        bool
 
-  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --urls-only
+	force         bool
 
+	apiParallel   int
 
+	sortBy        string
 
-  # Print only usernames of org/group members
+	sortReverse   bool
 
-  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --usernames-only
+	format        string
 
-  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --usernames-only
+	recursive     bool
 
+	showTree      bool
 
+	baseURL       string
 
-  # Download all org/group repos (max size 250MB)
+	profileName   string
 
-  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --download
+	configPath    string
 
-  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --download
+	profileOrgs   []string // org entries from the selected profile
 
+	tokenFile     string
 
+	tokenCmd      string
 
-  # Save results to a file
+	tokenSource   string // where the --token value came from, for reporting
 
-  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --output results.txt
+	tokenStrategy string
 
-  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --output results.txt
+	appID         string
 
+	appKeyPath    string
 
+	appInstallationID string
 
-  # Fetch for multiple orgs/groups listed in a file
+	noPreflight   bool
 
-  github-org-tool --provider github --token <TOKEN> --orgname orgs.txt --output results.txt
+	languageFilter  []string
 
-  github-org-tool --provider gitlab --token <TOKEN> --orgname groups.txt --output results.txt
+	languagesDetail bool
 
-`,
+	visibilityFilter []string
 
-		Run: func(cmd *cobra.Command, args []string) {
+	archivedMode     string
 
-			RunFetcher()
+	pushedSince      string
 
-		},
+	pushedBeforeFlag string
 
-	}
+	createdSince     string
 
+	updatedSince     string
 
+	includeFlag      []string
 
-	rootCmd.Flags().StringVarP(&provider, "provider", "p", "github", "Provider to use: github or gitlab") // NEW
+	excludeFlag      []string
 
-	rootCmd.Flags().StringVarP(&token, "token", "t", "", "Personal access token (optional for GitLab public info, required for GitHub/private)")
+	excludeFile      string
 
-	rootCmd.Flags().StringVarP(&orgname, "orgname", "o", "", "Organization (GitHub) or group (GitLab) name (required)")
+	whereFlag        string
 
-	rootCmd.Flags().StringVarP(&output, "output", "O", "", "Write results to output file")
+	topicFilter      []string
 
-	rootCmd.Flags().BoolVarP(&includeForks, "include-forks", "f", false, "Include forked repositories in the output")
-
-	rootCmd.Flags().StringVarP(&repoType, "repo-type", "r", "org", "Type of repositories to fetch: 'org' for organization/group repos, 'member' for member/user-owned repos, 'both' for all")
-
-	rootCmd.Flags().StringVarP(&member, "member", "m", "", "Username to fetch repos for particular user/member (only used with --repo-type member)")
+	topicMatch       string
 
-	rootCmd.Flags().BoolVarP(&download, "download", "d", false, "Download all listed repositories using git clone")
-
-	rootCmd.Flags().BoolVarP(&urlsOnly, "urls-only", "u", false, "Print only repo URLs (no names)")
-
-	rootCmd.Flags().BoolVarP(&usernamesOnly, "usernames-only", "U", false, "Print only usernames of organization/group members (one per line)")
-
-	rootCmd.Flags().IntVarP(&maxSizeMB, "max-size", "s", 250, "Maximum repo size (MB) to clone")
-
-	rootCmd.Flags().IntVarP(&parallel, "parallel", "P", 4, "Number of concurrent clones when using --download")
-
-	rootCmd.MarkFlagRequired("orgname")
-
-	// Remove required flag for token if provider is gitlab
-
-	cobra.OnInitialize(func() {
-
-		if provider == "gitlab" && token == "" {
-
-			// Don't require token for GitLab public info
-
-			_ = rootCmd.Flags().SetAnnotation("token", cobra.BashCompOneRequiredFlag, nil)
-
-		}
-
-	})
-
-
-
-	if err := rootCmd.Execute(); err != nil {
-
-		fmt.Println(err)
-
-		os.Exit(1)
-
-	}
-
-}
-
-
-
-func getOrgList(orgname string) ([]string, error) {
-
-	if fi, err := os.Stat(orgname); err == nil && !fi.IsDir() {
-
-		// orgname is a file, read org names from file
-
-		file, err := os.Open(orgname)
-
-		if err != nil {
-
-			return nil, err
-
-		}
-
-		defer file.Close()
+	topicsReport     bool
 
-		var orgs []string
+	roleFilter       []string
 
-		scanner := bufio.NewScanner(file)
+	twoFADisabled    bool
 
-		for scanner.Scan() {
+	exitCode         int // set by RunFetcher when the run must exit non-zero
 
-			line := scanner.Text()
-
-			if line != "" {
-
-				orgs = append(orgs, line)
-
-			}
-
-		}
-
-		if err := scanner.Err(); err != nil {
-
-			return nil, err
-
-		}
-
-		return orgs, nil
-
-	}
-
-	// orgname is a single org
-
-	return []string{orgname}, nil
-
-}
-
-
-
-func RunFetcher() {
-
-	orgs, err := getOrgList(orgname)
-
-	if err != nil {
-
-		fmt.Printf("Error reading orgname(s): %v\n", err)
-
-		return
-
-	}
-
-
-
-	var f *os.File
-
-	if output != "" {
-
-		f, err = os.Create(output)
-
-		if err != nil {
-
-			fmt.Printf("Error creating output file: %v\n", err)
-
-			return
-
-		}
-
-		defer f.Close()
-
-	}
-
-
-
-	totalMembers := 0
-
-	totalRepos := 0
-
-	totalMemberRepos := 0
-
-
-
-	for _, org := range orgs {
-
-		if provider == "gitlab" {
-
-			// GitLab logic
-
-			if usernamesOnly {
-
-				members, err := fetchGitLabMembers(token, org)
-
-				if err != nil {
-
-					if f != nil {
-
-						fmt.Fprintf(f, "Error fetching members: %v\n", err)
-
-					} else {
-
-						fmt.Printf("Error fetching members: %v\n", err)
-
-					}
-
-					continue
-
-				}
-
-				for _, m := range members {
-
-					if f != nil {
-
-						fmt.Fprintf(f, "%s\n", m.Username)
-
-					} else {
-
-						fmt.Println(m.Username)
-
-					}
-
-				}
-
-				totalMembers += len(members)
-
-				continue
-
-			}
-
-			if urlsOnly {
-
-				if repoType == "org" {
-
-					repos, err := fetchGitLabRepos(token, org)
-
-					if err != nil {
-
-						if f != nil {
-
-							fmt.Fprintf(f, "Error fetching repos: %v\n", err)
-
-						} else {
-
-							fmt.Printf("Error fetching repos: %v\n", err)
-
-						}
-
-						continue
-
-					}
-
-					for _, r := range repos {
-
-						if !includeForks && r.Fork {
-
-							continue
-
-						}
-
-						if f != nil {
-
-							fmt.Fprintf(f, "https://gitlab.com/%s/%s\n", org, r.Name)
-
-						} else {
-
-							fmt.Printf("https://gitlab.com/%s/%s\n", org, r.Name)
-
-						}
-
-						totalRepos++
-
-					}
-
-					continue
-
-				} else if repoType == "member" {
-
-					var members []string
-
-					if member != "" {
-
-						members = []string{member}
-
-					} else {
-
-						mlist, err := fetchGitLabMembers(token, org)
-
-						if err != nil {
-
-							if f != nil {
-
-								fmt.Fprintf(f, "Error fetching members: %v\n", err)
-
-							} else {
-
-								fmt.Printf("Error fetching members: %v\n", err)
-
-							}
-
-							continue
-
-						}
-
-						for _, m := range mlist {
-
-							members = append(members, m.Username)
-
-						}
-
-					}
-
-					for _, m := range members {
-
-						repos, err := fetchGitLabUserRepos(token, m)
-
-						if err != nil {
-
-							if f != nil {
-
-								fmt.Fprintf(f, "Error fetching repos for %s: %v\n", m, err)
-
-							} else {
-
-								fmt.Printf("Error fetching repos for %s: %v\n", m, err)
-
-							}
-
-							continue
-
-						}
-
-						for _, r := range repos {
-
-							if !includeForks && r.Fork {
-
-								continue
-
-							}
+)
 
-							if f != nil {
 
-								fmt.Fprintf(f, "https://gitlab.com/%s/%s\n", m, r.Name)
 
-							} else {
+func main() {
 
-								fmt.Printf("https://gitlab.com/%s/%s\n", m, r.Name)
+	var rootCmd = &cobra.Command{
 
-							}
+		Use:   "github-org-tool",
 
-							totalMemberRepos++
+		Short: "Fetch and report organization/group members and repositories from GitHub or GitLab.",
 
-						}
+		Long: `GitHub/GitLab Organization Automation Tool
 
-					}
+-----------------------------------
 
-					continue
+Fetch and report organization/group members and repositories from GitHub or GitLab with advanced filtering and output options.
 
-				} else if repoType == "both" {
+Supports organization-wide and member-owned repositories, colored console output, and download link generation.
 
-					repos, err := fetchGitLabRepos(token, org)
 
-					if err != nil {
 
-						if f != nil {
+Main Features:
 
-							fmt.Fprintf(f, "Error fetching repos: %v\n", err)
+  - Fetch organization/group members and repositories (GitHub or GitLab)
 
-						} else {
+  - Filter by member, repo type, and fork status
 
-							fmt.Printf("Error fetching repos: %v\n", err)
+  - Filter repo names with --include/--exclude globs or re: regexps, and --exclude-file
 
-						}
+  - Combine conditions on any repo field with --where expressions
 
-						continue
+  - Filter by topic (--topic, --topic-match any|all) and report topic usage (--topics-report)
 
-					}
+  - Print colored output and repo URLs
 
-					for _, r := range repos {
+  - Download repositories (with size limit)
 
-						if !includeForks && r.Fork {
+  - Concurrent API fetching across orgs and members (--api-parallel), rate limit aware
 
-							continue
+  - Preview downloads with --dry-run (sizes, skipped repos, free disk space)
 
-						}
+  - Output results to file as text, JSON or CSV (--format), sorted with --sort/--reverse
 
-						if f != nil {
+  - Read multiple orgs/groups from a file (pass filename to --orgname), mixing providers and instances
 
-							fmt.Fprintf(f, "https://gitlab.com/%s/%s\n", org, r.Name)
+    with provider:name or provider@URL:name entries and per-line token=env:NAME / token=file:PATH
 
-						} else {
+  - Read org names from stdin (--orgname - or piped input)
 
-							fmt.Printf("https://gitlab.com/%s/%s\n", org, r.Name)
+  - Named profiles in ~/.config/orgfetch/config.yaml (--profile), overridden by flags
 
-						}
+  - Tokens from --token-file, --token-cmd, GITHUB_TOKEN/GITLAB_TOKEN/ORGFETCH_TOKEN, ~/.netrc or the gh/glab config
 
-						totalRepos++
+  - Print only repo URLs (--urls-only) or only usernames (--usernames-only)
 
-					}
+  - Member roles (GitHub admin/member, GitLab access levels with expiry) and --role filtering
 
-					var members []string
+  - List members without two-factor authentication (--2fa-disabled), exiting non-zero when any are found
 
-					if member != "" {
+  - Flexible repo type selection: org, member, both
 
-						members = []string{member}
+  - All flags have short forms for usability
 
-					} else {
+  - Select provider: --provider github|gitlab|bitbucket|gitea|azure
 
-						mlist, err := fetchGitLabMembers(token, org)
+  - GitLab subgroup recursion (--recursive) and group tree view (--tree)
 
-						if err != nil {
+`,
 
-							if f != nil {
+		Example: `
 
-								fmt.Fprintf(f, "Error fetching members: %v\n", err)
+  # Fetch all GitHub org repos (default, forks excluded)
 
-							} else {
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG>
 
-								fmt.Printf("Error fetching members: %v\n", err)
 
-							}
 
-							continue
+  # Fetch all GitLab group repos
 
-						}
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP>
 
-						for _, m := range mlist {
 
-							members = append(members, m.Username)
 
-						}
+  # Fetch all Bitbucket Cloud workspace repos (app password auth)
 
-					}
+  github-org-tool --provider bitbucket --token <USER>:<APP_PASSWORD> --orgname <WORKSPACE>
 
-					for _, m := range members {
 
-						repos, err := fetchGitLabUserRepos(token, m)
 
-						if err != nil {
+  # Fetch all repos of a self-hosted Gitea/Forgejo organization
 
-							if f != nil {
+  github-org-tool --provider gitea --base-url https://gitea.example.com --token <TOKEN> --orgname <ORG>
 
-								fmt.Fprintf(f, "Error fetching repos for %s: %v\n", m, err)
 
-							} else {
 
-								fmt.Printf("Error fetching repos for %s: %v\n", m, err)
+  # List Azure DevOps repos of every project in an organization, or of one project
 
-							}
+  github-org-tool --provider azure --token <PAT> --orgname <ORGANIZATION>
 
-							continue
+  github-org-tool --provider azure --token <PAT> --orgname <ORGANIZATION>/<PROJECT> --urls-only
 
-						}
 
-						for _, r := range repos {
 
-							if !includeForks && r.Fork {
+  # Fetch all org/group repos, including forks
 
-								continue
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --include-forks
 
-							}
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --include-forks
 
-							if f != nil {
 
-								fmt.Fprintf(f, "https://gitlab.com/%s/%s\n", m, r.Name)
 
-							} else {
+  # Include all nested GitLab subgroups, or print the group hierarchy with counts
 
-								fmt.Printf("https://gitlab.com/%s/%s\n", m, r.Name)
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --recursive
 
-							}
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --tree
 
-							totalMemberRepos++
 
-						}
 
-					}
+  # Fetch member-owned repos for all members/users
 
-					continue
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --repo-type member
 
-				}
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --repo-type member
 
-			}
 
-			// ...other output modes for GitLab (full info, download, etc.)
 
-			continue
+  # Print only repo URLs
 
-		}
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --urls-only
 
-		if usernamesOnly {
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --urls-only
 
-			orgMembers, err := fetchMembers(token, org)
 
-			if err != nil {
 
-				if f != nil {
+  # Print only usernames of org/group members
 
-					fmt.Fprintf(f, "Error fetching members: %v\n", err)
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --usernames-only
 
-				} else {
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --usernames-only
 
-					fmt.Printf("Error fetching members: %v\n", err)
 
-				}
 
-				continue
+  # Download all org/group repos (max size 250MB)
 
-			}
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --download
 
-			for _, m := range orgMembers {
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --download
 
-				if f != nil {
 
-					fmt.Fprintf(f, "%s\n", m.Login)
 
-				} else {
+  # Show what --download would clone, with sizes and a free space check
 
-					fmt.Println(m.Login)
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --dry-run
 
-				}
 
-			}
 
-			totalMembers += len(orgMembers)
+  # Export a JSON report, largest repos first
 
-			continue
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --format json --sort size --reverse
 
-		}
 
-		if urlsOnly {
 
-			if repoType == "org" {
+  # Save results to a file
 
-				repos, err := fetchRepos(token, org)
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --output results.txt
 
-				if err != nil {
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --output results.txt
 
-					if f != nil {
 
-						fmt.Fprintf(f, "Error fetching repos: %v\n", err)
 
-					} else {
+  # Fetch for multiple orgs/groups listed in a file
 
-						fmt.Printf("Error fetching repos: %v\n", err)
+  github-org-tool --provider github --token <TOKEN> --orgname orgs.txt --output results.txt
 
-					}
+  github-org-tool --provider gitlab --token <TOKEN> --orgname groups.txt --output results.txt
 
-					continue
 
-				}
 
-				for _, r := range repos {
+  # An org file can mix providers and instances; lines without a prefix use --provider/--token
 
-					if !includeForks && r.Fork {
+  #   acme
 
-						continue
+  #   gitlab:acme-group
 
-					}
+  #   gitlab@https://git.corp:platform token=env:CORP_GITLAB_TOKEN
 
-					if f != nil {
+  github-org-tool --provider github --token <TOKEN> --orgname estate.txt --format csv
 
-						fmt.Fprintf(f, "https://github.com/%s/%s\n", org, r.Name)
 
-					} else {
 
-						fmt.Printf("https://github.com/%s/%s\n", org, r.Name)
+  # Keep the token out of shell history
 
-					}
+  github-org-tool --provider github --token-cmd 'pass show github/orgfetch' --orgname <ORG>
 
-					totalRepos++
+  GITHUB_TOKEN=<TOKEN> github-org-tool --provider github --orgname <ORG>
 
-				}
 
-				continue
 
-			} else if repoType == "member" {
+  # Spread a large audit over a pool of GitHub tokens (one per line)
 
-				var members []string
+  github-org-tool --provider github --token-file tokens.txt --token-strategy most-remaining --orgname orgs.txt
 
-				if member != "" {
 
-					members = []string{member}
 
-				} else {
+  # Authenticate as a GitHub App installed on the org
 
-					orgMembers, err := fetchMembers(token, org)
+  github-org-tool --provider github --app-id <APP_ID> --app-key app.pem --orgname <ORG>
 
-					if err != nil {
 
-						if f != nil {
 
-							fmt.Fprintf(f, "Error fetching members: %v\n", err)
+  # Mirror only active repos, or list only public ones
 
-						} else {
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --archived exclude --download
 
-							fmt.Printf("Error fetching members: %v\n", err)
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --visibility public --urls-only
 
-						}
 
-						continue
 
-					}
+  # Clone everything except sandbox repos and a curated skip list
 
-					for _, m := range orgMembers {
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --exclude 'sandbox-*' --exclude-file skip.txt --download
 
-						members = append(members, m.Login)
 
-					}
 
-				}
+  # Small, active Go repos tagged security
 
-				for _, m := range members {
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --where 'size < 50MB && !archived && language == "Go" && "security" in topics'
 
-					repos, err := fetchUserRepos(token, m)
 
-					if err != nil {
 
-						if f != nil {
+  # Repos tagged both security and go, and how consistently repos are tagged
 
-							fmt.Fprintf(f, "Error fetching repos for %s: %v\n", m, err)
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --topic security,go --topic-match all
 
-						} else {
+  github-org-tool --provider gitlab --token <TOKEN> --orgname groups.txt --topics-report
 
-							fmt.Printf("Error fetching repos for %s: %v\n", m, err)
 
-						}
 
-						continue
+  # Org owners and GitLab maintainers, with their roles
 
-					}
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --role admin --usernames-only
 
-					for _, r := range repos {
+  github-org-tool --provider gitlab --token <TOKEN> --orgname <GROUP> --role maintainer,owner --format csv --usernames-only
 
-						if !includeForks && r.Fork {
 
-							continue
 
-						}
+  # Compliance check: members without 2FA, exit status 1 when any are found
 
-						if f != nil {
+  github-org-tool --provider github --token <TOKEN> --orgname orgs.txt --2fa-disabled
 
-							fmt.Fprintf(f, "https://github.com/%s/%s\n", m, r.Name)
 
-						} else {
 
-							fmt.Printf("https://github.com/%s/%s\n", m, r.Name)
+  # Stale repos not pushed to for a year
 
-						}
+  github-org-tool --provider github --token <TOKEN> --orgname <ORG> --pushed-before 1y --format csv
 
-						totalMemberRepos++
 
-					}
 
-				}
+  # All Go and Python repos of several orgs, with language breakdowns
 
-				continue
+  github-org-tool --provider github --token <TOKEN> --orgname orgs.txt --language go,python --languages-detail
 
-			} else if repoType == "both" {
 
-				repos, err := fetchRepos(token, org)
 
-				if err != nil {
+  # Check who the token belongs to, its scopes and its expiry
 
-					if f != nil {
+  github-org-tool whoami --provider github
 
-						fmt.Fprintf(f, "Error fetching org repos: %v\n", err)
 
-					} else {
 
-						fmt.Printf("Error fetching org repos: %v\n", err)
+  # Run a profile from the config file, overriding its output format
 
-					}
+  github-org-tool --profile estate --format json
 
-				} else {
 
-					for _, r := range repos {
 
-						if !includeForks && r.Fork {
+  # Read org names from stdin
 
-							continue
+  gh api user/orgs --jq '.[].login' | github-org-tool --token <TOKEN> --orgname - --urls-only
 
-						}
+`,
 
-						if f != nil {
+		Run: func(cmd *cobra.Command, args []string) {
 
-							fmt.Fprintf(f, "https://github.com/%s/%s\n", org, r.Name)
+			if profileName != "" {
 
-						} else {
+				if err := applyProfile(configPath, profileName, cmd.Flags()); err != nil {
 
-							fmt.Printf("https://github.com/%s/%s\n", org, r.Name)
+					fmt.Printf("Error: %v\n", err)
 
-						}
+					if twoFADisabled {
 
-						totalRepos++
+						os.Exit(1)
 
 					}
 
-				}
+					return
 
-				var members []string
+				}
 
-				if member != "" {
+			}
 
-					members = []string{member}
+			RunFetcher()
 
-				} else {
+			if exitCode != 0 {
 
-					orgMembers, err := fetchMembers(token, org)
+				os.Exit(exitCode)
 
-					if err != nil {
+			}
 
-						if f != nil {
+		},
 
-							fmt.Fprintf(f, "Error fetching members: %v\n", err)
+	}
 
-						} else {
 
-							fmt.Printf("Error fetching members: %v\n", err)
 
-						}
+	whoamiCmd := &cobra.Command{
 
-						continue
+		Use:   "whoami",
 
-					}
+		Short: "Show the user, scopes and expiry of the tokens in use, with warnings for missing scopes",
 
-					for _, m := range orgMembers {
+		Example: `
 
-						members = append(members, m.Login)
+  github-org-tool whoami
 
-					}
+  github-org-tool whoami --provider gitlab --token-file ~/.gitlab-token
 
-				}
+  github-org-tool whoami --orgname orgs.txt`,
 
-				for _, m := range members {
+		Run: func(cmd *cobra.Command, args []string) {
 
-					repos, err := fetchUserRepos(token, m)
+			if profileName != "" {
 
-					if err != nil {
+				if err := applyProfile(configPath, profileName, cmd.Flags(), rootCmd.Flags()); err != nil {
 
-						if f != nil {
+					fmt.Printf("Error: %v\n", err)
 
-							fmt.Fprintf(f, "Error fetching repos for %s: %v\n", m, err)
+					return
 
-						} else {
+				}
 
-							fmt.Printf("Error fetching repos for %s: %v\n", m, err)
+			}
 
-						}
+			RunWhoami()
 
-						continue
+		},
 
-					}
+	}
 
-					for _, r := range repos {
+	rootCmd.AddCommand(whoamiCmd)
 
-						if !includeForks && r.Fork {
 
-							continue
 
-						}
+	rootCmd.PersistentFlags().StringVarP(&provider, "provider", "p", "github", "Provider to use: github, gitlab, bitbucket, gitea (Gitea/Forgejo) or azure (Azure DevOps)") // NEW
 
-						if f != nil {
+	rootCmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "Base URL of a self-hosted instance: Gitea/Forgejo (required with --provider gitea), GitLab or GitHub Enterprise Server")
 
-							fmt.Fprintf(f, "https://github.com/%s/%s\n", m, r.Name)
+	rootCmd.PersistentFlags().StringVarP(&token, "token", "t", "", "Personal access token (optional for GitLab public info, required for GitHub/private); comma-separate several GitHub tokens to rotate through them")
 
-						} else {
+	rootCmd.PersistentFlags().StringVar(&tokenFile, "token-file", "", "Read the token from this file instead of --token (one token per line for a GitHub token pool)")
 
-							fmt.Printf("https://github.com/%s/%s\n", m, r.Name)
+	rootCmd.PersistentFlags().StringVar(&tokenStrategy, "token-strategy", "round-robin", "GitHub token pool rotation: round-robin or most-remaining (rate limit)")
 
-						}
+	rootCmd.PersistentFlags().StringVar(&tokenCmd, "token-cmd", "", "Run this command (e.g. a password manager) and use the first line of its output as the token")
 
-						totalMemberRepos++
+	rootCmd.PersistentFlags().StringVar(&appID, "app-id", "", "Authenticate to GitHub as this GitHub App (App ID or client ID) instead of a token")
 
-					}
+	rootCmd.PersistentFlags().StringVar(&appKeyPath, "app-key", "", "PEM private key file of the GitHub App (with --app-id)")
 
-				}
+	rootCmd.PersistentFlags().StringVar(&appInstallationID, "app-installation-id", "", "GitHub App installation to use (default: looked up for each org)")
 
-				continue
+	rootCmd.PersistentFlags().StringVarP(&orgname, "orgname", "o", "", "Organization (GitHub) or group (GitLab) name, a file of entries, or - for stdin (required unless piped); GitLab accepts a full group path or numeric ID")
 
-			}
+	rootCmd.Flags().StringVarP(&output, "output", "O", "", "Write results to output file")
 
-		}
+	rootCmd.Flags().BoolVarP(&includeForks, "include-forks", "f", false, "Include forked repositories in the output")
 
-		if repoType == "org" {
+	rootCmd.Flags().StringVarP(&repoType, "repo-type", "r", "org", "Type of repositories to fetch: 'org' for organization/group repos, 'member' for member/user-owned repos, 'both' for all")
 
-			repos, err := fetchRepos(token, org)
+	rootCmd.Flags().StringVarP(&member, "member", "m", "", "Username to fetch repos for particular user/member (only used with --repo-type member)")
 
-			if err != nil {
+	rootCmd.Flags().BoolVarP(&download, "download", "d", false, "Download all listed repositories using git clone")
 
-				if f != nil {
+	rootCmd.Flags().BoolVarP(&urlsOnly, "urls-only", "u", false, "Print only repo URLs (no names)")
 
-					fmt.Fprintf(f, "Error fetching repos: %v\n", err)
+	rootCmd.Flags().BoolVarP(&usernamesOnly, "usernames-only", "U", false, "Print only usernames of organization/group members (one per line)")
 
-				} else {
+	rootCmd.Flags().IntVarP(&maxSizeMB, "max-size", "s", 250, "Maximum repo size (MB) to clone")
 
-					fmt.Printf("Error fetching repos: %v\n", err)
+	rootCmd.Flags().IntVarP(&parallel, "parallel", "P", 4, "Number of concurrent clones when using --download")
 
-				}
+	rootCmd.Flags().IntVarP(&apiParallel, "api-parallel", "A", 4, "Number of concurrent API requests when fetching orgs and members")
 
-				continue
+	rootCmd.Flags().BoolVarP(&recursive, "recursive", "R", false, "GitLab: include projects and members of all descendant subgroups")
 
-			}
+	rootCmd.Flags().BoolVar(&showTree, "tree", false, "GitLab: print the group hierarchy as a tree with per-subgroup project and member counts (implies --recursive)")
 
-			if f != nil {
+	rootCmd.Flags().StringArrayVar(&includeFlag, "include", nil, "Only list repos whose name or owner/name matches this glob (or re:<regexp>); repeatable")
 
-				fmt.Fprintf(f, "Organization: %s\n", org)
+	rootCmd.Flags().StringArrayVar(&excludeFlag, "exclude", nil, "Skip repos whose name or owner/name matches this glob (or re:<regexp>); repeatable")
 
-			} else {
+	rootCmd.Flags().StringVar(&excludeFile, "exclude-file", "", "File of --exclude patterns, one per line (# comments allowed)")
 
-				fmt.Printf("Organization: %s\n", org)
+	rootCmd.Flags().StringVar(&whereFlag, "where", "", `Only list repos matching this expression, e.g. 'size < 50MB && !archived && "security" in topics'`)
 
-			}
+	rootCmd.Flags().BoolVar(&twoFADisabled, "2fa-disabled", false, "List only members without two-factor authentication, per org; exits with status 1 when any are found")
 
-			for _, r := range repos {
+	rootCmd.Flags().StringSliceVar(&roleFilter, "role", nil, "Only list members with these roles: GitHub admin or member, GitLab guest, reporter, developer, maintainer or owner (comma-separated)")
 
-				if !includeForks && r.Fork {
+	rootCmd.Flags().StringSliceVar(&topicFilter, "topic", nil, "Only list repos with these topics (GitLab: tags), comma-separated and case-insensitive")
 
-					continue
+	rootCmd.Flags().StringVar(&topicMatch, "topic-match", "any", "With several --topic values, require any or all of them")
 
-				}
+	rootCmd.Flags().BoolVar(&topicsReport, "topics-report", false, "Print every topic of the listed repos with repo counts instead of the repos")
 
-				if f != nil {
+	rootCmd.Flags().StringSliceVar(&visibilityFilter, "visibility", nil, "Only list repos with this visibility: public, private or internal (comma-separated for several)")
 
-					fmt.Fprintf(f, "Repo: %s\n", r.Name)
+	rootCmd.Flags().StringVar(&archivedMode, "archived", "include", "Archived repos: include, exclude or only")
 
-					fmt.Fprintf(f, "  URL: https://github.com/%s/%s\n", org, r.Name)
+	rootCmd.Flags().StringVar(&pushedSince, "pushed-since", "", "Only list repos pushed to since this date (2006-01-02) or within this duration (90d, 12w, 1y)")
 
-					fmt.Fprintf(f, "  Fork: %v\n", r.Fork)
+	rootCmd.Flags().StringVar(&pushedBeforeFlag, "pushed-before", "", "Only list repos last pushed to before this date or longer ago than this duration (stale repos)")
 
-					fmt.Fprintf(f, "  Size (KB): %d\n", r.Size)
+	rootCmd.Flags().StringVar(&createdSince, "created-since", "", "Only list repos created since this date or within this duration")
 
-					fmt.Fprintf(f, "  Owner: %s\n", r.Owner.Login)
+	rootCmd.Flags().StringVar(&updatedSince, "updated-since", "", "Only list repos updated since this date or within this duration")
 
-				} else {
+	rootCmd.Flags().StringSliceVar(&languageFilter, "language", nil, "Only list repos whose primary language is one of these (comma-separated, case-insensitive)")
 
-					fmt.Printf("Repo: %s\n", r.Name)
+	rootCmd.Flags().BoolVar(&languagesDetail, "languages-detail", false, "Fetch each repo's language breakdown (bytes/percent) and report an aggregate per org (GitHub, GitLab, Gitea)")
 
-					fmt.Printf("  URL: https://github.com/%s/%s\n", org, r.Name)
+	rootCmd.Flags().StringVar(&sortBy, "sort", "name", "Sort repos by: name, size, owner, pushed, created (applies to all output formats and the download queue)")
 
-					fmt.Printf("  Fork: %v\n", r.Fork)
+	rootCmd.Flags().BoolVar(&sortReverse, "reverse", false, "Reverse the --sort order")
 
-					fmt.Printf("  Size (KB): %d\n", r.Size)
+	rootCmd.Flags().StringVar(&format, "format", "text", "Output format: text, json or csv")
 
-					fmt.Printf("  Owner: %s\n", r.Owner.Login)
+	rootCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Print the download plan (repos, sizes, skipped repos, free space) without cloning")
 
-				}
+	rootCmd.Flags().BoolVarP(&force, "force", "F", false, "Start --download even when the estimated size exceeds the free disk space")
 
-			}
+	rootCmd.Flags().BoolVar(&noPreflight, "no-preflight", false, "Skip the token check (identity, scopes, expiry) before fetching")
 
-			members, err := fetchMembers(token, org)
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Load settings from this profile of the config file (command-line flags override them)")
 
-			if err != nil {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", defaultConfigPath(), "Config file with named profiles")
 
-				if f != nil {
+	// Remove required flag for token if provider is gitlab
 
-					fmt.Fprintf(f, "Error fetching members: %v\n", err)
+	cobra.OnInitialize(func() {
 
-				} else {
+		if provider == "gitlab" && token == "" {
 
-					fmt.Printf("Error fetching members: %v\n", err)
+			// Don't require token for GitLab public info
 
-				}
+			_ = rootCmd.Flags().SetAnnotation("token", cobra.BashCompOneRequiredFlag, nil)
 
-				continue
+		}
 
-			}
+	})
 
-			for _, m := range members {
 
-				if f != nil {
 
-					fmt.Fprintf(f, "Member: %s\n", m.Login)
+	if err := rootCmd.Execute(); err != nil {
 
-				} else {
+		fmt.Println(err)
 
-					fmt.Printf("Member: %s\n", m.Login)
+		os.Exit(1)
 
-				}
+	}
 
-			}
+}
 
-			totalMembers += len(members)
 
-			continue
 
-		} else if repoType == "member" {
+func getOrgList(orgname string) ([]orgTarget, error) {
 
-			var members []string
+	if orgname == "-" {
 
-			if member != "" {
+		// read org entries from stdin, e.g. piped from another tool
 
-				members = []string{member}
+		return readOrgList(os.Stdin, "stdin")
 
-			} else {
+	}
 
-				orgMembers, err := fetchMembers(token, org)
+	if fi, err := os.Stat(orgname); err == nil && !fi.IsDir() {
 
-				if err != nil {
+		// orgname is a file, read org entries from file
 
-					if f != nil {
+		file, err := os.Open(orgname)
 
-						fmt.Fprintf(f, "Error fetching members: %v\n", err)
+		if err != nil {
 
-					} else {
+			return nil, err
 
-						fmt.Printf("Error fetching members: %v\n", err)
+		}
 
-					}
+		defer file.Close()
 
-					continue
+		return readOrgList(file, orgname)
 
-				}
+	}
 
-				for _, m := range orgMembers {
+	// orgname is a single org
 
-					members = append(members, m.Login)
+	t, err := parseOrgLine(orgname)
 
-				}
+	if err != nil {
 
-			}
+		return nil, err
 
-			for _, m := range members {
+	}
 
-				repos, err := fetchUserRepos(token, m)
+	return []orgTarget{t}, nil
 
-				if err != nil {
+}
 
-					if f != nil {
 
-						fmt.Fprintf(f, "Error fetching repos for %s: %v\n", m, err)
 
-					} else {
+// readOrgList reads one org entry per line; src names the input in error messages
 
-						fmt.Printf("Error fetching repos for %s: %v\n", m, err)
+func readOrgList(r io.Reader, src string) ([]orgTarget, error) {
 
-					}
+	var orgs []orgTarget
 
-					continue
+	scanner := bufio.NewScanner(r)
 
-				}
+	for n := 1; scanner.Scan(); n++ {
 
-				for _, r := range repos {
+		line := strings.TrimSpace(scanner.Text())
 
-					if !includeForks && r.Fork {
+		// skip blank lines and # comments
 
-						continue
+		if line == "" || strings.HasPrefix(line, "#") {
 
-					}
+			continue
 
-					if f != nil {
+		}
 
-						fmt.Fprintf(f, "%s/%s\n", m, r.Name)
+		if i := strings.Index(line, " #"); i != -1 {
 
-					} else {
+			line = strings.TrimSpace(line[:i])
 
-						fmt.Printf("%s/%s\n", m, r.Name)
+		}
 
-					}
+		t, err := parseOrgLine(line)
 
-					totalMemberRepos++
+		if err != nil {
 
-				}
+			return nil, fmt.Errorf("%s:%d: %v", src, n, err)
 
-			}
+		}
 
-			for _, m := range members {
+		orgs = append(orgs, t)
 
-				if f != nil {
+	}
 
-					fmt.Fprintf(f, "Member: %s\n", m)
+	if err := scanner.Err(); err != nil {
 
-				} else {
+		return nil, err
 
-					fmt.Printf("Member: %s\n", m)
+	}
 
-				}
+	if len(orgs) == 0 {
 
-			}
+		return nil, fmt.Errorf("%s: no org names found", src)
 
-			continue
+	}
 
-		} else if repoType == "both" {
+	return orgs, nil
 
-			repos, err := fetchRepos(token, org)
+}
 
-			if err != nil {
 
-				if f != nil {
 
-					fmt.Fprintf(f, "Error fetching org repos: %v\n", err)
+// stdinPiped reports whether stdin is a pipe or file rather than a terminal
 
-				} else {
+func stdinPiped() bool {
 
-					fmt.Printf("Error fetching org repos: %v\n", err)
+	fi, err := os.Stdin.Stat()
 
-				}
+	return err == nil && fi.Mode()&os.ModeCharDevice == 0
 
-			} else {
+}
 
-				if f != nil {
 
-					fmt.Fprintf(f, "Organization: %s\n", org)
 
-				} else {
+func RunFetcher() {
 
-					fmt.Printf("Organization: %s\n", org)
+	if twoFADisabled {
 
-				}
+		// a compliance check fails unless it gets to check every org and finds
 
-				for _, r := range repos {
+		// nobody without 2FA, so every error return below exits non-zero
 
-					if !includeForks && r.Fork {
+		exitCode = 1
 
-						continue
+	}
 
-					}
+	if err := validateSort(); err != nil {
 
-					if f != nil {
+		fmt.Printf("Error: %v\n", err)
 
-						fmt.Fprintf(f, "Repo: %s\n", r.Name)
+		return
 
-						fmt.Fprintf(f, "  URL: https://github.com/%s/%s\n", org, r.Name)
+	}
 
-						fmt.Fprintf(f, "  Fork: %v\n", r.Fork)
+	if err := validateFilters(); err != nil {
 
-						fmt.Fprintf(f, "  Size (KB): %d\n", r.Size)
+		fmt.Printf("Error: %v\n", err)
 
-						fmt.Fprintf(f, "  Owner: %s\n", r.Owner.Login)
+		return
 
-					} else {
+	}
 
-						fmt.Printf("Repo: %s\n", r.Name)
+	if format != "text" && format != "json" && format != "csv" {
 
-						fmt.Printf("  URL: https://github.com/%s/%s\n", org, r.Name)
+		fmt.Printf("Error: invalid --format %q (expected text, json or csv)\n", format)
 
-						fmt.Printf("  Fork: %v\n", r.Fork)
+		return
 
-						fmt.Printf("  Size (KB): %d\n", r.Size)
+	}
 
-						fmt.Printf("  Owner: %s\n", r.Owner.Login)
 
-					}
 
-				}
+	if twoFADisabled && (download || dryRun || urlsOnly || topicsReport || showTree) {
 
-			}
+		fmt.Println("Error: --2fa-disabled lists members only and cannot be combined with --download, --dry-run, --urls-only, --topics-report or --tree")
 
-			var members []string
+		return
 
-			if member != "" {
+	}
 
-				members = []string{member}
 
-			} else {
 
-				orgMembers, err := fetchMembers(token, org)
+	if !isProvider(provider) {
 
-				if err != nil {
+		fmt.Printf("Error: unknown provider %q (expected %s)\n", provider, strings.Join(providers, ", "))
 
-					if f != nil {
+		return
 
-						fmt.Fprintf(f, "Error fetching members: %v\n", err)
+	}
 
-					} else {
+	baseURL = strings.TrimRight(baseURL, "/")
 
-						fmt.Printf("Error fetching members: %v\n", err)
+	if tokenStrategy != "round-robin" && tokenStrategy != "most-remaining" {
 
-					}
+		fmt.Printf("Error: invalid --token-strategy %q (expected %s)\n", tokenStrategy, strings.Join(tokenStrategies, " or "))
 
-					continue
+		return
 
-				}
+	}
 
-				for _, m := range orgMembers {
+	if err := resolveFlagToken(); err != nil {
 
-					members = append(members, m.Login)
+		fmt.Printf("Error: %v\n", err)
 
-				}
+		return
 
-			}
+	}
 
-			for _, m := range members {
+	if showTree {
 
-				repos, err := fetchUserRepos(token, m)
+		recursive = true
 
-				if err != nil {
+	}
 
-					if f != nil {
 
-						fmt.Fprintf(f, "Error fetching repos for %s: %v\n", m, err)
 
-					} else {
+	var orgs []orgTarget
 
-						fmt.Printf("Error fetching repos for %s: %v\n", m, err)
+	var err error
 
-					}
+	if orgname == "" && len(profileOrgs) > 0 {
 
-					continue
+		orgs, err = readOrgList(strings.NewReader(strings.Join(profileOrgs, "\n")), "profile "+profileName)
 
-				}
+	} else {
 
-				for _, r := range repos {
+		if orgname == "" {
 
-					if !includeForks && r.Fork {
+			// without --orgname, org names may be piped in
 
-						continue
+			if !stdinPiped() {
 
-					}
+				fmt.Println(`Error: required flag "orgname" not set (pass a name, a file, or - to read from stdin)`)
 
-					if f != nil {
+				return
 
-						fmt.Fprintf(f, "%s/%s\n", m, r.Name)
+			}
 
-					} else {
+			orgname = "-"
 
-						fmt.Printf("%s/%s\n", m, r.Name)
+		}
 
-					}
+		orgs, err = getOrgList(orgname)
 
-					totalMemberRepos++
+	}
 
-				}
+	if err != nil {
 
-			}
+		fmt.Printf("Error reading orgname(s): %v\n", err)
 
-		}
+		return
 
 	}
 
-	// Print totals to console only (not to file)
+	if err := applyGitHubApp(orgs); err != nil {
 
-	if usernamesOnly {
+		fmt.Printf("Error: %v\n", err)
 
-		fmt.Printf("%sTotal members: %s%d%s\n", Yellow, Green, totalMembers, Reset)
+		return
 
-	} else if urlsOnly {
+	}
 
-		if repoType == "org" || repoType == "both" {
+	resolveTokens(orgs)
 
-			fmt.Printf("%sTotal repositories: %s%d%s\n", Yellow, Green, totalRepos, Reset)
+	if !noPreflight {
 
-		}
+		if err := preflight(orgs); err != nil {
 
-		if repoType == "member" || repoType == "both" {
+			fmt.Printf("Error: %v\n", err)
 
-			fmt.Printf("%sTotal member-owned repositories: %s%d%s\n", Yellow, Green, totalMemberRepos, Reset)
+			return
 
 		}
 
 	}
 
-	// Parallel download logic
+	orgs, err = expandAzureOrgs(orgs)
 
-	if download {
+	if err != nil {
 
-		var repoURLs []string
+		fmt.Printf("Error listing Azure DevOps organizations: %v\n", err)
 
-		mainFolder := "downloaded_repos"
+		return
 
-		_ = os.MkdirAll(mainFolder, 0755)
+	}
 
-		// Collect all repo URLs to clone
+	hasGitLab := false
 
-		for _, org := range orgs {
+	noLanguages := map[string]bool{}
 
-			if provider == "github" {
+	noTopics := map[string]bool{}
 
-				repos, err := fetchRepos(token, org)
+	noRoles := map[string]bool{}
 
-				if err != nil {
+	for _, t := range orgs {
 
-					fmt.Printf("Error fetching repos: %v\n", err)
+		if err := validateTarget(t); err != nil {
 
-					continue
+			fmt.Printf("Error: %v\n", err)
 
-				}
+			return
 
-				for _, r := range repos {
+		}
 
-					if !includeForks && r.Fork {
+		if twoFADisabled && !twoFASupported(t.Provider) {
 
-						continue
+			fmt.Printf("Error: --2fa-disabled is only supported for GitHub and GitLab, not %s\n", t.Provider)
 
-					}
+			return
 
-					url := fmt.Sprintf("https://github.com/%s/%s", org, r.Name)
+		}
 
-					repoURLs = append(repoURLs, url)
+		hasGitLab = hasGitLab || t.Provider == "gitlab"
 
-				}
+		if languagesDetail && !languagesSupported(t.Provider) && !noLanguages[t.Provider] {
 
-				if repoType == "member" || repoType == "both" {
+			noLanguages[t.Provider] = true
 
-					var members []string
+			fmt.Fprintf(os.Stderr, "Warning: %s has no language breakdowns; --languages-detail is ignored for its repos\n", t.Provider)
 
-					if member != "" {
+		}
 
-						members = []string{member}
+		if len(roleFilter) > 0 && t.Provider != "github" && t.Provider != "gitlab" && !noRoles[t.Provider] {
 
-					} else {
+			noRoles[t.Provider] = true
 
-						orgMembers, err := fetchMembers(token, org)
+			fmt.Fprintf(os.Stderr, "Warning: %s does not report member roles; --role matches none of its members\n", t.Provider)
 
-						if err != nil {
+		}
 
-							fmt.Printf("Error fetching members: %v\n", err)
+		if (len(topicFilter) > 0 || topicsReport) && !topicsSupported(t.Provider) && !noTopics[t.Provider] {
 
-							continue
+			noTopics[t.Provider] = true
 
-						}
+			fmt.Fprintf(os.Stderr, "Warning: %s does not report repo topics; --topic and --topics-report see its repos as untagged\n", t.Provider)
 
-						for _, m := range orgMembers {
+		}
 
-							members = append(members, m.Login)
+	}
 
-						}
+	if recursive && !hasGitLab {
 
-					}
+		fmt.Println("Error: --recursive and --tree are only supported for GitLab groups")
 
-					for _, m := range members {
+		return
 
-						repos, err := fetchUserRepos(token, m)
+	}
 
-						if err != nil {
 
-							fmt.Printf("Error fetching repos for %s: %v\n", m, err)
 
-							continue
+	var w io.Writer = os.Stdout
 
-						}
+	if output != "" {
 
-						for _, r := range repos {
+		f, err := os.Create(output)
 
-							if !includeForks && r.Fork {
+		if err != nil {
 
-								continue
+			fmt.Printf("Error creating output file: %v\n", err)
 
-							}
+			return
 
-							url := fmt.Sprintf("https://github.com/%s/%s", m, r.Name)
+		}
 
-							repoURLs = append(repoURLs, url)
+		defer f.Close()
 
-						}
+		w = f
 
-					}
+	}
 
-				}
 
-			} else if provider == "gitlab" {
 
-				repos, err := fetchGitLabRepos(token, org)
+	// Single fetch phase: the listing and the download plan share the same data
 
-				if err != nil {
+	data := fetchAll(orgs)
 
-					fmt.Printf("Error fetching repos: %v\n", err)
+	sortData(data)
 
-					continue
 
-				}
 
-				for _, r := range repos {
+	totals := printReport(w, data)
 
-					if !includeForks && r.Fork {
+	// keep stdout machine-readable when JSON/CSV goes there
 
-						continue
+	if format == "text" || output != "" {
 
-					}
+		printTotals(totals)
 
-					url := fmt.Sprintf("https://gitlab.com/%s/%s", org, r.Name)
+	}
 
-					repoURLs = append(repoURLs, url)
+	if twoFADisabled && !no2FAFailed(data) {
 
-				}
+		// every org was checked and nobody lacks 2FA
 
-				if repoType == "member" || repoType == "both" {
+		exitCode = 0
 
-					var members []string
+	}
 
-					if member != "" {
 
-						members = []string{member}
 
-					} else {
+	if download || dryRun {
 
-						mlist, err := fetchGitLabMembers(token, org)
+		// the plan and clone progress follow the report, so they go to stderr
 
-						if err != nil {
+		// when JSON/CSV on stdout must stay machine-readable
 
-							fmt.Printf("Error fetching members: %v\n", err)
+		var progress io.Writer = os.Stdout
 
-							continue
+		if format != "text" && output == "" {
 
-						}
+			progress = os.Stderr
 
-						for _, m := range mlist {
+		}
 
-							members = append(members, m.Username)
+		plan := buildPlan("downloaded_repos", data)
 
-						}
+		if dryRun {
 
-					}
+			plan.print(progress)
 
-					for _, m := range members {
+			return
 
-						repos, err := fetchGitLabUserRepos(token, m)
+		}
 
-						if err != nil {
+		// Refuse to start when the estimate does not fit on the destination filesystem
 
-							fmt.Printf("Error fetching repos for %s: %v\n", m, err)
+		if free, ok, err := plan.checkSpace(); err != nil {
 
-							continue
+			fmt.Fprintf(progress, "Warning: could not check free space on %s: %v\n", plan.Folder, err)
 
-						}
+		} else if !ok && !force {
 
-						for _, r := range repos {
+			fmt.Fprintf(progress, "Insufficient disk space on %s: need %s, have %s (use --force to download anyway)\n",
 
-							if !includeForks && r.Fork {
+				plan.Folder, formatBytes(plan.totalBytes()), formatBytes(int64(free)))
 
-								continue
+			return
 
-							}
+		}
 
-							url := fmt.Sprintf("https://gitlab.com/%s/%s", m, r.Name)
+		downloadRepos(plan, progress)
 
-							repoURLs = append(repoURLs, url)
+	}
 
-						}
+}
 
-					}
 
-				}
 
-			}
+// RunWhoami reports the identity, scopes and expiry of the token of every
 
-		}
+// provider/instance in --orgname, or of --provider/--base-url without it
 
-		// Parallel worker pool
+func RunWhoami() {
 
-		type job struct {
+	if !isProvider(provider) {
 
-			url string
+		fmt.Printf("Error: unknown provider %q (expected %s)\n", provider, strings.Join(providers, ", "))
 
-		}
+		return
 
-		jobs := make(chan job, len(repoURLs))
+	}
 
-		results := make(chan string, len(repoURLs))
+	baseURL = strings.TrimRight(baseURL, "/")
 
-		for w := 0; w < parallel; w++ {
+	if err := resolveFlagToken(); err != nil {
 
-			go func() {
+		fmt.Printf("Error: %v\n", err)
 
-				for j := range jobs {
+		return
 
-					err := cloneRepo(j.url, mainFolder)
+	}
 
-					if err != nil {
+	orgs := []orgTarget{{Provider: provider, BaseURL: baseURL, Token: token, TokenSource: tokenSource}}
 
-						results <- fmt.Sprintf("Failed: %s (%v)", j.url, err)
+	var err error
 
-					} else {
+	if orgname != "" {
 
-						results <- fmt.Sprintf("Cloned: %s", j.url)
+		orgs, err = getOrgList(orgname)
 
-					}
+	} else if len(profileOrgs) > 0 {
 
-				}
+		orgs, err = readOrgList(strings.NewReader(strings.Join(profileOrgs, "\n")), "profile "+profileName)
 
-			}()
+	}
 
-		}
+	if err != nil {
 
-		for _, url := range repoURLs {
+		fmt.Printf("Error reading orgname(s): %v\n", err)
 
-			jobs <- job{url: url}
+		return
 
-		}
+	}
 
-		close(jobs)
+	if err := applyGitHubApp(orgs); err != nil {
 
-		for i := 0; i < len(repoURLs); i++ {
+		fmt.Printf("Error: %v\n", err)
 
-			fmt.Println(<-results)
+		return
 
-		}
+	}
 
-		return // skip rest of RunFetcher if download is used
+	resolveTokens(orgs)
 
-	}
+	printWhoami(os.Stdout, orgs)
 
 }
 
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RepoInfo is the provider-neutral view of a repository shared by the output and download phases
+type RepoInfo struct {
+	ID            string          `json:"id,omitempty"` // GitLab project ID, for follow-up requests
+	Name          string          `json:"name"`
+	Owner         string          `json:"owner"`
+	URL           string          `json:"url"` // web URL
+	CloneURL      string          `json:"clone_url,omitempty"`
+	Description   string          `json:"description,omitempty"`
+	Visibility    string          `json:"visibility,omitempty"` // public, private or internal
+	Archived      bool            `json:"archived"`
+	Disabled      bool            `json:"disabled"`
+	Fork          bool            `json:"fork"`
+	DefaultBranch string          `json:"default_branch,omitempty"`
+	Language      string          `json:"language,omitempty"`
+	Topics        []string        `json:"topics,omitempty"`
+	Languages     []LanguageShare `json:"languages,omitempty"` // only with --languages-detail
+	Stars         int             `json:"stars"`
+	Forks         int             `json:"forks"`
+	OpenIssues    int             `json:"open_issues"`
+	License       string          `json:"license,omitempty"`
+	Size          int64           `json:"size_bytes"`
+	Pushed        time.Time       `json:"pushed_at"`
+	Created       time.Time       `json:"created_at"`
+	Updated       time.Time       `json:"updated_at"`
+}
+
+// gitURL is the URL to clone from, falling back to the web URL, which every
+// supported provider also accepts
+func (r RepoInfo) gitURL() string {
+	if r.CloneURL != "" {
+		return r.CloneURL
+	}
+	return r.URL
+}
+
+// MemberInfo is the provider-neutral view of an org/group member
+type MemberInfo struct {
+	Login   string `json:"login"`
+	ID      string `json:"id,omitempty"`         // provider user ID, when member repos are looked up by ID
+	Role    string `json:"role,omitempty"`       // GitHub admin/member, GitLab Guest to Owner
+	Expires string `json:"expires_at,omitempty"` // GitLab membership expiry date
+	level   int    // GitLab access level, to keep the highest role across groups
+}
+
+// MemberRepos holds the repositories owned by a single member
+type MemberRepos struct {
+	Member string     `json:"member"`
+	Repos  []RepoInfo `json:"repos"`
+	Err    error      `json:"-"`
+}
+
+// OrgData is everything fetched for one org/group. Fetch errors are kept next to
+// the data so the output phase can report them where the data would have been.
+type OrgData struct {
+	Target      orgTarget `json:"-"`
+	Name        string    // as given on the command line or in the org file
+	Path        string    // resolved GitLab group path
+	ID          string    // resolved GitLab group ID, used for all follow-up requests
+	Repos       []RepoInfo
+	RepoErr     error
+	Members     []MemberInfo
+	MemberErr   error
+	MemberRepos []MemberRepos
+	Groups      []GroupInfo // GitLab group hierarchy, only with --recursive
+	GroupErr    error
+}
+
+// fetchAll runs the single fetch phase for every org. Orgs and members are fetched
+// concurrently, but results are stored by index so output order stays deterministic.
+func fetchAll(orgs []orgTarget) []*OrgData {
+	data := make([]*OrgData, len(orgs))
+	forEach(len(orgs), func(i int) {
+		data[i] = fetchOrgData(orgs[i])
+	})
+	return data
+}
+
+// fetchOrgData fetches only what the selected output and download modes need
+func fetchOrgData(t orgTarget) *OrgData {
+	d := &OrgData{Target: t, Name: t.Name}
+	ref := t.Name
+	if t.Provider == "gitlab" {
+		// validate the group up front and use its numeric ID from here on
+		g, err := fetchGitLabGroup(t.apiBase(), t.Token, t.Name)
+		if err != nil {
+			err = fmt.Errorf("group %q: %v", t.Name, err)
+			d.RepoErr, d.MemberErr, d.GroupErr = err, err, err
+			return d
+		}
+		d.Path, d.ID = g.FullPath, strconv.Itoa(g.ID)
+		ref = d.ID
+	}
+	if t.Provider == "gitlab" && recursive {
+		d.Groups, d.GroupErr = fetchGroupTree(t, d.Path, d.ID, wantMembers())
+	}
+	if twoFADisabled {
+		d.Members, d.MemberErr = fetchNo2FAMembers(t, ref, d.Groups, d.GroupErr)
+		if len(roleFilter) > 0 {
+			d.Members = filterMembers(d.Members)
+		}
+		return d
+	}
+	if wantOrgRepos() {
+		d.Repos, d.RepoErr = fetchOrgRepos(t, ref)
+	}
+	if wantMembers() {
+		if d.Groups != nil {
+			d.Members, d.MemberErr = mergeGroupMembers(d.Groups), d.GroupErr
+		} else {
+			d.Members, d.MemberErr = fetchOrgMembers(t, ref)
+		}
+		if len(roleFilter) > 0 {
+			d.Members = filterMembers(d.Members)
+		}
+	}
+	countGroupProjects(d.Groups, d.Repos)
+	if wantMemberRepos() {
+		users := []MemberInfo{{Login: member}}
+		if member == "" {
+			users = d.Members
+		}
+		d.MemberRepos = make([]MemberRepos, len(users))
+		forEach(len(users), func(i int) {
+			repos, err := fetchMemberRepos(t, users[i])
+			d.MemberRepos[i] = MemberRepos{Member: users[i].Login, Repos: repos, Err: err}
+		})
+	}
+	if wantLanguages(t) {
+		enrichLanguages(t, d.Repos)
+		for i := range d.MemberRepos {
+			enrichLanguages(t, d.MemberRepos[i].Repos)
+		}
+	}
+	return d
+}
+
+// forEach calls fn for every index in [0, n) on at most --api-parallel goroutines.
+// The API requests themselves are additionally bounded and paced by the rate limiter.
+func forEach(n int, fn func(i int)) {
+	limit := apiParallel
+	if limit < 1 {
+		limit = 1
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// label names the org in output headers, with the resolved GitLab path and ID when known
+func (d *OrgData) label() string {
+	if d.ID != "" {
+		return d.Target.label(fmt.Sprintf("%s (ID %s)", d.Path, d.ID))
+	}
+	return d.Target.label(d.Name)
+}
+
+func wantOrgRepos() bool {
+	return showTree || repoType != "member" && (!usernamesOnly || download || dryRun)
+}
+
+func wantMemberRepos() bool {
+	return (repoType == "member" || repoType == "both") && (!usernamesOnly || download || dryRun)
+}
+
+func wantMembers() bool {
+	if usernamesOnly || showTree || twoFADisabled {
+		return true
+	}
+	// the full org report lists members after the repos
+	if repoType == "org" && !urlsOnly {
+		return true
+	}
+	return wantMemberRepos() && member == ""
+}
+
+// fetchOrgRepos returns the repos owned by an org/group on the target's provider
+func fetchOrgRepos(t orgTarget, org string) ([]RepoInfo, error) {
+	switch t.Provider {
+	case "gitlab":
+		repos, err := fetchGitLabRepos(t.apiBase(), t.Token, org, recursive, pushedAfter, pushedBefore)
+		return fromGitLabRepos(repos), err
+	case "github":
+		repos, err := fetchRepos(t.apiBase(), t.Token, org)
+		return fromGitHubRepos(repos), err
+	case "bitbucket":
+		repos, err := fetchBitbucketRepos(t.Token, org)
+		return fromBitbucketRepos(repos), err
+	case "gitea":
+		repos, err := fetchGiteaRepos(t.BaseURL, t.Token, org)
+		return fromGiteaRepos(repos), err
+	case "azure":
+		return fetchAzureOrgRepos(t, org)
+	}
+	return nil, fmt.Errorf("unknown provider %q", t.Provider)
+}
+
+// fetchOrgMembers returns the members of an org/group on the target's provider
+func fetchOrgMembers(t orgTarget, org string) ([]MemberInfo, error) {
+	var out []MemberInfo
+	switch t.Provider {
+	case "gitlab":
+		members, err := fetchGitLabMembers(t.apiBase(), t.Token, org)
+		for _, m := range members {
+			out = append(out, m.info())
+		}
+		return out, err
+	case "github":
+		members, err := fetchMembers(t.apiBase(), t.Token, org, "all")
+		for _, m := range members {
+			out = append(out, MemberInfo{Login: m.Login, Role: m.Role})
+		}
+		return out, err
+	case "bitbucket":
+		members, err := fetchBitbucketMembers(t.Token, org)
+		for _, m := range members {
+			out = append(out, MemberInfo{Login: m.User.Nickname, ID: m.User.UUID})
+		}
+		return out, err
+	case "gitea":
+		members, err := fetchGiteaMembers(t.BaseURL, t.Token, org)
+		for _, m := range members {
+			out = append(out, MemberInfo{Login: m.Login})
+		}
+		return out, err
+	case "azure":
+		azureOrg, _ := splitAzureOrg(org)
+		members, err := fetchAzureMembers(t.Token, azureOrg)
+		for _, m := range members {
+			out = append(out, MemberInfo{Login: m.User.PrincipalName})
+		}
+		return out, err
+	}
+	return nil, fmt.Errorf("unknown provider %q", t.Provider)
+}
+
+// fetchMemberRepos returns the repos owned by a single user on the target's provider
+func fetchMemberRepos(t orgTarget, m MemberInfo) ([]RepoInfo, error) {
+	switch t.Provider {
+	case "gitlab":
+		repos, err := fetchGitLabUserRepos(t.apiBase(), t.Token, m.Login, pushedAfter, pushedBefore)
+		return fromGitLabRepos(repos), err
+	case "github":
+		repos, err := fetchUserRepos(t.apiBase(), t.Token, m.Login)
+		return fromGitHubRepos(repos), err
+	case "bitbucket":
+		// personal workspaces are addressed by the user's UUID; --member falls back to the name
+		workspace := m.ID
+		if workspace == "" {
+			workspace = m.Login
+		}
+		repos, err := fetchBitbucketRepos(t.Token, workspace)
+		return fromBitbucketRepos(repos), err
+	case "gitea":
+		repos, err := fetchGiteaUserRepos(t.BaseURL, t.Token, m.Login)
+		return fromGiteaRepos(repos), err
+	case "azure":
+		return nil, fmt.Errorf("Azure DevOps has no user-owned repositories")
+	}
+	return nil, fmt.Errorf("unknown provider %q", t.Provider)
+}
+
+// fetchLanguages returns the language breakdown of a repo, largest share first
+func fetchLanguages(t orgTarget, r RepoInfo) ([]LanguageShare, error) {
+	switch t.Provider {
+	case "github":
+		langs, err := fetchRepoLanguages(t.apiBase(), t.Token, r.Owner, r.Name)
+		return sharesFromBytes(langs), err
+	case "gitlab":
+		langs, err := fetchGitLabLanguages(t.apiBase(), t.Token, r.ID)
+		return sharesFromPercent(langs), err
+	case "gitea":
+		langs, err := fetchGiteaLanguages(t.BaseURL, t.Token, r.Owner, r.Name)
+		return sharesFromBytes(langs), err
+	}
+	return nil, fmt.Errorf("language breakdowns are not available for %s", t.Provider)
+}
+
+func fromGitHubRepos(repos []Repo) []RepoInfo {
+	var out []RepoInfo
+	for _, r := range repos {
+		info := RepoInfo{
+			Name:          r.Name,
+			Owner:         r.Owner.Login,
+			URL:           r.HTMLURL,
+			CloneURL:      r.CloneURL,
+			Description:   r.Description,
+			Visibility:    r.Visibility,
+			Archived:      r.Archived,
+			Disabled:      r.Disabled,
+			Fork:          r.Fork,
+			DefaultBranch: r.DefaultBranch,
+			Language:      r.Language,
+			Topics:        r.Topics,
+			Stars:         r.StargazersCount,
+			Forks:         r.ForksCount,
+			OpenIssues:    r.OpenIssuesCount,
+			Size:          int64(r.Size) * 1024,
+			Pushed:        r.PushedAt,
+			Created:       r.CreatedAt,
+			Updated:       r.UpdatedAt,
+		}
+		if info.Visibility == "" {
+			// GitHub Enterprise Server before 3.x only reports private
+			info.Visibility = visibilityOf(r.Private)
+		}
+		if r.License != nil {
+			info.License = r.License.SPDXID
+			if info.License == "" || info.License == "NOASSERTION" {
+				info.License = r.License.Name
+			}
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+func visibilityOf(private bool) string {
+	if private {
+		return "private"
+	}
+	return "public"
+}
+
+func fromGitLabRepos(repos []GitLabRepo) []RepoInfo {
+	var out []RepoInfo
+	for _, r := range repos {
+		out = append(out, RepoInfo{
+			ID:            strconv.Itoa(r.ID),
+			Name:          r.Name,
+			Owner:         r.Namespace.FullPath,
+			URL:           r.WebURL,
+			CloneURL:      r.HTTPURLToRepo,
+			Description:   r.Description,
+			Visibility:    r.Visibility,
+			Archived:      r.Archived,
+			Fork:          r.Fork,
+			DefaultBranch: r.DefaultBranch,
+			Topics:        r.Topics,
+			Stars:         r.StarCount,
+			Forks:         r.ForksCount,
+			OpenIssues:    r.OpenIssuesCount,
+			Size:          r.Statistics.RepositorySize,
+			Pushed:        r.LastActivityAt, // GitLab has no push time; last activity includes pushes
+			Created:       r.CreatedAt,
+			Updated:       r.UpdatedAt,
+		})
+	}
+	return out
+}
+
+func fromBitbucketRepos(repos []BitbucketRepo) []RepoInfo {
+	var out []RepoInfo
+	for _, r := range repos {
+		owner, _, _ := strings.Cut(r.FullName, "/")
+		info := RepoInfo{
+			Name:        r.Name,
+			Owner:       owner,
+			URL:         r.Links.HTML.Href,
+			Description: r.Description,
+			Visibility:  visibilityOf(r.IsPrivate),
+			Fork:        r.Parent != nil,
+			Language:    r.Language,
+			Size:        r.Size,
+			Pushed:      r.UpdatedOn,
+			Created:     r.CreatedOn,
+			Updated:     r.UpdatedOn,
+		}
+		if r.MainBranch != nil {
+			info.DefaultBranch = r.MainBranch.Name
+		}
+		for _, c := range r.Links.Clone {
+			if c.Name == "https" {
+				info.CloneURL = c.Href
+			}
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+func fromGiteaRepos(repos []GiteaRepo) []RepoInfo {
+	var out []RepoInfo
+	for _, r := range repos {
+		info := RepoInfo{
+			Name:          r.Name,
+			Owner:         r.Owner.Login,
+			URL:           r.HTMLURL,
+			CloneURL:      r.CloneURL,
+			Description:   r.Description,
+			Visibility:    visibilityOf(r.Private),
+			Archived:      r.Archived,
+			Fork:          r.Fork,
+			DefaultBranch: r.DefaultBranch,
+			Language:      r.Language,
+			Topics:        r.Topics,
+			Stars:         r.StarsCount,
+			Forks:         r.ForksCount,
+			OpenIssues:    r.OpenIssuesCount,
+			License:       strings.Join(r.Licenses, ", "),
+			Size:          r.Size * 1024,
+			Pushed:        r.UpdatedAt,
+			Created:       r.CreatedAt,
+			Updated:       r.UpdatedAt,
+		}
+		if r.Internal {
+			info.Visibility = "internal"
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+// fetchAzureOrgRepos lists the repos of every project of an Azure DevOps organization,
+// or of a single project when the org is given as org/project
+func fetchAzureOrgRepos(t orgTarget, name string) ([]RepoInfo, error) {
+	org, project := splitAzureOrg(name)
+	projects := []string{project}
+	if project == "" {
+		list, err := fetchAzureProjects(t.Token, org)
+		if err != nil {
+			return nil, err
+		}
+		projects = nil
+		for _, p := range list {
+			projects = append(projects, p.Name)
+		}
+	}
+	results := make([][]AzureRepo, len(projects))
+	errs := make([]error, len(projects))
+	forEach(len(projects), func(i int) {
+		results[i], errs[i] = fetchAzureRepos(t.Token, org, projects[i])
+	})
+	var out []RepoInfo
+	for i, repos := range results {
+		if errs[i] != nil {
+			return out, fmt.Errorf("project %s: %v", projects[i], errs[i])
+		}
+		for _, r := range repos {
+			out = append(out, RepoInfo{
+				Name:          r.Name,
+				Owner:         org + "/" + r.Project.Name,
+				URL:           r.WebURL,
+				CloneURL:      r.RemoteURL,
+				Visibility:    r.Project.Visibility,
+				Disabled:      r.IsDisabled,
+				Fork:          r.IsFork,
+				DefaultBranch: strings.TrimPrefix(r.DefaultBranch, "refs/heads/"),
+				Size:          r.Size,
+			})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportTotals counts what was printed, for the summary lines on the console
+type reportTotals struct {
+	Members     int
+	Repos       int
+	MemberRepos int
+}
+
+// printReport writes the listing for every org in the selected mode and --format
+func printReport(w io.Writer, data []*OrgData) reportTotals {
+	if twoFADisabled {
+		return printNo2FA(w, data)
+	}
+	if topicsReport {
+		return printTopics(w, data)
+	}
+	switch format {
+	case "json":
+		return printJSON(w, data)
+	case "csv":
+		return printCSV(w, data)
+	}
+	var t reportTotals
+	for _, d := range data {
+		switch {
+		case showTree:
+			printTree(w, d)
+		case usernamesOnly:
+			printUsernames(w, d, &t)
+		case urlsOnly:
+			printURLs(w, d, &t)
+		default:
+			printFull(w, d, &t)
+			if languagesDetail {
+				printOrgLanguages(w, d)
+			}
+		}
+	}
+	return t
+}
+
+// orgReport is the JSON shape of one org: the fetched model after filtering
+type orgReport struct {
+	Provider    string          `json:"provider"`
+	BaseURL     string          `json:"base_url,omitempty"`
+	Org         string          `json:"org"`
+	Path        string          `json:"path,omitempty"`
+	ID          string          `json:"id,omitempty"`
+	Repos       []RepoInfo      `json:"repos,omitempty"`
+	Members     []MemberInfo    `json:"members,omitempty"`
+	MemberRepos []MemberRepos   `json:"member_repos,omitempty"`
+	Groups      []GroupInfo     `json:"groups,omitempty"`
+	Languages   []LanguageShare `json:"languages,omitempty"` // aggregate of all listed repos, with --languages-detail
+	Errors      []string        `json:"errors,omitempty"`
+}
+
+func printJSON(w io.Writer, data []*OrgData) reportTotals {
+	var t reportTotals
+	reports := []orgReport{}
+	for _, d := range data {
+		r := orgReport{Provider: d.Target.Provider, BaseURL: d.Target.BaseURL, Org: d.Name, Path: d.Path, ID: d.ID, Members: d.Members, Groups: d.Groups}
+		if d.GroupErr != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("fetching groups: %v", d.GroupErr))
+		}
+		if d.RepoErr != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("fetching repos: %v", d.RepoErr))
+		}
+		if d.MemberErr != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("fetching members: %v", d.MemberErr))
+		}
+		if !usernamesOnly {
+			r.Repos = filterRepos(d.Repos)
+			for _, mr := range d.MemberRepos {
+				if mr.Err != nil {
+					r.Errors = append(r.Errors, fmt.Sprintf("fetching repos for %s: %v", mr.Member, mr.Err))
+					continue
+				}
+				mr.Repos = filterRepos(mr.Repos)
+				r.MemberRepos = append(r.MemberRepos, mr)
+				t.MemberRepos += len(mr.Repos)
+			}
+		}
+		if languagesDetail {
+			r.Languages = aggregateLanguages(listedRepos(r.Repos, r.MemberRepos))
+		}
+		t.Repos += len(r.Repos)
+		t.Members += len(r.Members)
+		reports = append(reports, r)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(reports); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+	}
+	return t
+}
+
+// printCSV writes one row per member with --usernames-only, otherwise one row per repo.
+// Fetch errors cannot be represented in the rows and go to stderr instead.
+func printCSV(w io.Writer, data []*OrgData) reportTotals {
+	var t reportTotals
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+	if showTree {
+		cw.Write([]string{"org", "group", "projects", "members"})
+		for _, d := range data {
+			if d.GroupErr != nil {
+				fmt.Fprintf(os.Stderr, "Error fetching groups for %s: %v\n", d.Name, d.GroupErr)
+			}
+			for _, g := range d.Groups {
+				cw.Write([]string{d.Name, g.Path, strconv.Itoa(g.Projects), strconv.Itoa(g.Members)})
+			}
+		}
+		return t
+	}
+	if usernamesOnly {
+		cw.Write([]string{"provider", "org", "login", "role", "expires_at"})
+		for _, d := range data {
+			if d.MemberErr != nil {
+				fmt.Fprintf(os.Stderr, "Error fetching members for %s: %v\n", d.Name, d.MemberErr)
+				continue
+			}
+			for _, m := range d.Members {
+				cw.Write([]string{d.Target.Provider, d.Name, m.Login, m.Role, m.Expires})
+			}
+			t.Members += len(d.Members)
+		}
+		return t
+	}
+	cw.Write([]string{"provider", "org", "source", "owner", "name", "url", "fork", "size_bytes", "pushed_at", "created_at",
+		"updated_at", "clone_url", "description", "visibility", "archived", "disabled", "default_branch", "language",
+		"topics", "stars", "forks", "open_issues", "license", "languages"})
+	row := func(d *OrgData, source string, r RepoInfo) {
+		cw.Write([]string{d.Target.Provider, d.Name, source, r.Owner, r.Name, r.URL, strconv.FormatBool(r.Fork),
+			strconv.FormatInt(r.Size, 10), csvTime(r.Pushed), csvTime(r.Created),
+			csvTime(r.Updated), r.CloneURL, r.Description, r.Visibility, strconv.FormatBool(r.Archived), strconv.FormatBool(r.Disabled),
+			r.DefaultBranch, r.Language, strings.Join(r.Topics, ";"), strconv.Itoa(r.Stars), strconv.Itoa(r.Forks),
+			strconv.Itoa(r.OpenIssues), r.License, csvLanguages(r.Languages)})
+	}
+	for _, d := range data {
+		if d.RepoErr != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching repos for %s: %v\n", d.Name, d.RepoErr)
+		}
+		if d.MemberErr != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching members for %s: %v\n", d.Name, d.MemberErr)
+		}
+		if repoType != "member" {
+			for _, r := range filterRepos(d.Repos) {
+				row(d, "org", r)
+				t.Repos++
+			}
+		}
+		for _, mr := range d.MemberRepos {
+			if mr.Err != nil {
+				fmt.Fprintf(os.Stderr, "Error fetching repos for %s: %v\n", mr.Member, mr.Err)
+				continue
+			}
+			for _, r := range filterRepos(mr.Repos) {
+				row(d, "member", r)
+				t.MemberRepos++
+			}
+		}
+	}
+	return t
+}
+
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// printTotals prints the summary lines to the console only (not to the output file)
+func printTotals(t reportTotals) {
+	if twoFADisabled {
+		fmt.Printf("%sTotal members without 2FA: %s%d%s\n", Yellow, Green, t.Members, Reset)
+	} else if usernamesOnly {
+		fmt.Printf("%sTotal members: %s%d%s\n", Yellow, Green, t.Members, Reset)
+	} else if urlsOnly {
+		if repoType == "org" || repoType == "both" {
+			fmt.Printf("%sTotal repositories: %s%d%s\n", Yellow, Green, t.Repos, Reset)
+		}
+		if repoType == "member" || repoType == "both" {
+			fmt.Printf("%sTotal member-owned repositories: %s%d%s\n", Yellow, Green, t.MemberRepos, Reset)
+		}
+	}
+}
+
+func printUsernames(w io.Writer, d *OrgData, t *reportTotals) {
+	if d.MemberErr != nil {
+		fmt.Fprintf(w, "Error fetching members: %v\n", d.MemberErr)
+		return
+	}
+	for _, m := range d.Members {
+		fmt.Fprintln(w, m.Login)
+	}
+	t.Members += len(d.Members)
+}
+
+func printURLs(w io.Writer, d *OrgData, t *reportTotals) {
+	if repoType == "org" || repoType == "both" {
+		if d.RepoErr != nil {
+			fmt.Fprintf(w, "Error fetching repos: %v\n", d.RepoErr)
+		}
+		for _, r := range filterRepos(d.Repos) {
+			fmt.Fprintln(w, r.URL)
+			t.Repos++
+		}
+	}
+	if repoType == "member" || repoType == "both" {
+		if !printMemberErr(w, d) {
+			return
+		}
+		for _, mr := range d.MemberRepos {
+			if mr.Err != nil {
+				fmt.Fprintf(w, "Error fetching repos for %s: %v\n", mr.Member, mr.Err)
+				continue
+			}
+			for _, r := range filterRepos(mr.Repos) {
+				fmt.Fprintln(w, r.URL)
+				t.MemberRepos++
+			}
+		}
+	}
+}
+
+func printFull(w io.Writer, d *OrgData, t *reportTotals) {
+	if repoType == "org" || repoType == "both" {
+		if d.RepoErr != nil {
+			fmt.Fprintf(w, "Error fetching repos: %v\n", d.RepoErr)
+			if repoType == "org" {
+				return
+			}
+		} else {
+			if d.Target.Provider == "gitlab" {
+				fmt.Fprintf(w, "Group: %s\n", d.label())
+			} else {
+				fmt.Fprintf(w, "Organization: %s\n", d.label())
+			}
+			for _, r := range filterRepos(d.Repos) {
+				printRepo(w, r)
+				t.Repos++
+			}
+		}
+	}
+	if repoType == "org" {
+		if !printMemberErr(w, d) {
+			return
+		}
+		for _, m := range d.Members {
+			fmt.Fprintf(w, "Member: %s\n", memberLabel(m))
+		}
+		t.Members += len(d.Members)
+		return
+	}
+	if !printMemberErr(w, d) {
+		return
+	}
+	for _, mr := range d.MemberRepos {
+		if mr.Err != nil {
+			fmt.Fprintf(w, "Error fetching repos for %s: %v\n", mr.Member, mr.Err)
+			continue
+		}
+		for _, r := range filterRepos(mr.Repos) {
+			fmt.Fprintf(w, "%s/%s\n", mr.Member, r.Name)
+			t.MemberRepos++
+		}
+	}
+	if repoType == "member" {
+		for _, mr := range d.MemberRepos {
+			fmt.Fprintf(w, "Member: %s\n", mr.Member)
+		}
+	}
+}
+
+// memberLabel renders a member as "alice (admin)" or "bob (Developer, expires 2026-12-31)"
+func memberLabel(m MemberInfo) string {
+	switch {
+	case m.Role != "" && m.Expires != "":
+		return fmt.Sprintf("%s (%s, expires %s)", m.Login, m.Role, m.Expires)
+	case m.Role != "":
+		return fmt.Sprintf("%s (%s)", m.Login, m.Role)
+	}
+	return m.Login
+}
+
+// printOrgLanguages writes the language aggregate of every repo listed for an org
+func printOrgLanguages(w io.Writer, d *OrgData) {
+	var repos []RepoInfo
+	if repoType != "member" {
+		repos = filterRepos(d.Repos)
+	}
+	var memberRepos []MemberRepos
+	if repoType != "org" {
+		for _, mr := range d.MemberRepos {
+			mr.Repos = filterRepos(mr.Repos)
+			memberRepos = append(memberRepos, mr)
+		}
+	}
+	if shares := aggregateLanguages(listedRepos(repos, memberRepos)); len(shares) > 0 {
+		fmt.Fprintf(w, "Languages (%s): %s\n", d.label(), formatLanguages(shares))
+	}
+}
+
+// listedRepos joins org repos and member repos into one list
+func listedRepos(repos []RepoInfo, memberRepos []MemberRepos) []RepoInfo {
+	all := append([]RepoInfo(nil), repos...)
+	for _, mr := range memberRepos {
+		all = append(all, mr.Repos...)
+	}
+	return all
+}
+
+// printRepo writes the details of one repo in the full text report; fields the
+// provider does not report are left out
+func printRepo(w io.Writer, r RepoInfo) {
+	fmt.Fprintf(w, "Repo: %s\n", r.Name)
+	if r.Description != "" {
+		fmt.Fprintf(w, "  Description: %s\n", r.Description)
+	}
+	fmt.Fprintf(w, "  URL: %s\n", r.URL)
+	if r.CloneURL != "" {
+		fmt.Fprintf(w, "  Clone URL: %s\n", r.CloneURL)
+	}
+	if r.Visibility != "" {
+		fmt.Fprintf(w, "  Visibility: %s\n", r.Visibility)
+	}
+	fmt.Fprintf(w, "  Fork: %v\n", r.Fork)
+	fmt.Fprintf(w, "  Archived: %v\n", r.Archived)
+	if r.Disabled {
+		fmt.Fprintf(w, "  Disabled: %v\n", r.Disabled)
+	}
+	if r.DefaultBranch != "" {
+		fmt.Fprintf(w, "  Default branch: %s\n", r.DefaultBranch)
+	}
+	if r.Language != "" {
+		fmt.Fprintf(w, "  Language: %s\n", r.Language)
+	}
+	if len(r.Languages) > 0 {
+		fmt.Fprintf(w, "  Languages: %s\n", formatLanguages(r.Languages))
+	}
+	if len(r.Topics) > 0 {
+		fmt.Fprintf(w, "  Topics: %s\n", strings.Join(r.Topics, ", "))
+	}
+	if r.License != "" {
+		fmt.Fprintf(w, "  License: %s\n", r.License)
+	}
+	fmt.Fprintf(w, "  Stars: %d, Forks: %d, Open issues: %d\n", r.Stars, r.Forks, r.OpenIssues)
+	fmt.Fprintf(w, "  Size (KB): %d\n", r.Size/1024)
+	fmt.Fprintf(w, "  Owner: %s\n", r.Owner)
+	for _, ts := range []struct {
+		label string
+		t     time.Time
+	}{{"Created", r.Created}, {"Updated", r.Updated}, {"Pushed", r.Pushed}} {
+		if !ts.t.IsZero() {
+			fmt.Fprintf(w, "  %s: %s\n", ts.label, csvTime(ts.t))
+		}
+	}
+}
+
+// printMemberErr reports a failed member lookup and returns false when there is nothing more to print
+func printMemberErr(w io.Writer, d *OrgData) bool {
+	if d.MemberErr != nil {
+		fmt.Fprintf(w, "Error fetching members: %v\n", d.MemberErr)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// namePattern is one --include/--exclude pattern: a glob, or a regular
+// expression when prefixed with re:
+type namePattern struct {
+	raw  string
+	glob string
+	re   *regexp.Regexp
+}
+
+// compiled --include and --exclude (plus --exclude-file) patterns, set by validateFilters
+var includePatterns, excludePatterns []namePattern
+
+func compilePattern(raw string) (namePattern, error) {
+	p := namePattern{raw: raw}
+	if expr, ok := strings.CutPrefix(raw, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return p, err
+		}
+		p.re = re
+		return p, nil
+	}
+	p.glob = strings.ToLower(raw)
+	if _, err := path.Match(p.glob, ""); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+func compilePatterns(flag string, raws []string) ([]namePattern, error) {
+	var out []namePattern
+	for _, raw := range raws {
+		p, err := compilePattern(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %v", flag, raw, err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// readPatternFile reads one pattern per line, skipping blank lines and # comments
+func readPatternFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var raws []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		raws = append(raws, line)
+	}
+	return raws, scanner.Err()
+}
+
+// match reports whether the pattern matches the repo's name or owner/name.
+// Globs are case-insensitive; regular expressions can use (?i).
+func (p namePattern) match(r RepoInfo) bool {
+	full := r.Owner + "/" + r.Name
+	if p.re != nil {
+		return p.re.MatchString(r.Name) || p.re.MatchString(full)
+	}
+	for _, s := range []string{r.Name, full} {
+		if ok, _ := path.Match(p.glob, strings.ToLower(s)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nameSkip reports why the repo is excluded by --include/--exclude, or ""
+func nameSkip(r RepoInfo) string {
+	for _, p := range excludePatterns {
+		if p.match(r) {
+			return "excluded by " + p.raw
+		}
+	}
+	if len(includePatterns) == 0 {
+		return ""
+	}
+	for _, p := range includePatterns {
+		if p.match(r) {
+			return ""
+		}
+	}
+	return "not matched by --include"
+}
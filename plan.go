@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// planEntry is a single repository considered for download
+type planEntry struct {
+	RepoInfo
+	Reason string
+}
+
+// downloadPlan holds the repos that would be cloned and the ones that would be skipped
+type downloadPlan struct {
+	Folder  string
+	Clone   []planEntry
+	Skipped []planEntry
+}
+
+// buildPlan queues every fetched repo the selected repo type covers
+func buildPlan(folder string, data []*OrgData) *downloadPlan {
+	p := &downloadPlan{Folder: folder}
+	for _, d := range data {
+		if repoType != "member" {
+			for _, r := range d.Repos {
+				p.add(r)
+			}
+		}
+		for _, mr := range d.MemberRepos {
+			for _, r := range mr.Repos {
+				p.add(r)
+			}
+		}
+	}
+	// the queue follows --sort across all orgs, e.g. --sort size clones small repos first
+	sort.SliceStable(p.Clone, func(i, j int) bool { return repoLess(p.Clone[i].RepoInfo, p.Clone[j].RepoInfo) })
+	return p
+}
+
+// add queues a repo for cloning unless the filters or --max-size exclude it
+func (p *downloadPlan) add(r RepoInfo) {
+	e := planEntry{RepoInfo: r}
+	if reason := skipReason(r); reason != "" {
+		e.Reason = reason
+		p.Skipped = append(p.Skipped, e)
+	} else if maxSizeMB > 0 && r.Size > int64(maxSizeMB)*1024*1024 {
+		e.Reason = fmt.Sprintf("exceeds --max-size %d MB", maxSizeMB)
+		p.Skipped = append(p.Skipped, e)
+	} else {
+		p.Clone = append(p.Clone, e)
+	}
+}
+
+// totalBytes returns the estimated number of bytes needed to clone every queued repo
+func (p *downloadPlan) totalBytes() int64 {
+	var total int64
+	for _, e := range p.Clone {
+		total += e.Size
+	}
+	return total
+}
+
+// checkSpace compares the estimate against the free space on the destination filesystem.
+// The free space is 0 and err is set when it could not be determined.
+func (p *downloadPlan) checkSpace() (free uint64, ok bool, err error) {
+	free, err = freeDiskSpace(existingParent(p.Folder))
+	if err != nil {
+		return 0, true, err
+	}
+	return free, uint64(p.totalBytes()) <= free, nil
+}
+
+// print writes the plan to w, listing every repo with its size and skip reason
+func (p *downloadPlan) print(w io.Writer) {
+	fmt.Fprintf(w, "Download plan (destination: %s)\n", p.Folder)
+	for _, e := range p.Clone {
+		fmt.Fprintf(w, "  CLONE %s (%s)\n", e.URL, formatBytes(e.Size))
+	}
+	for _, e := range p.Skipped {
+		fmt.Fprintf(w, "  SKIP  %s (%s): %s\n", e.URL, formatBytes(e.Size), e.Reason)
+	}
+	fmt.Fprintf(w, "%sRepositories to clone: %s%d%s\n", Yellow, Green, len(p.Clone), Reset)
+	fmt.Fprintf(w, "%sRepositories skipped: %s%d%s\n", Yellow, Green, len(p.Skipped), Reset)
+	fmt.Fprintf(w, "%sEstimated download size: %s%s%s\n", Yellow, Green, formatBytes(p.totalBytes()), Reset)
+	free, ok, err := p.checkSpace()
+	if err != nil {
+		fmt.Fprintf(w, "Free space on %s: unknown (%v)\n", p.Folder, err)
+		return
+	}
+	fmt.Fprintf(w, "%sFree space on %s: %s%s%s\n", Yellow, p.Folder, Green, formatBytes(int64(free)), Reset)
+	if !ok {
+		fmt.Fprintf(w, "Insufficient disk space: need %s, have %s\n", formatBytes(p.totalBytes()), formatBytes(int64(free)))
+	}
+}
+
+// existingParent walks up from path until it finds a directory that exists,
+// so free space can be checked before the download folder is created.
+func existingParent(path string) string {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "."
+	}
+	for {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// tokenExpiryWarning is how close to its expiry a token is reported as expiring soon
+const tokenExpiryWarning = 7 * 24 * time.Hour
+
+// tokenInfo is what the provider reports about a token
+type tokenInfo struct {
+	Login       string
+	Scopes      []string
+	ScopesKnown bool // false for tokens without listed scopes (e.g. GitHub fine-grained tokens)
+	Expires     time.Time
+}
+
+// checkToken looks up the user, scopes and expiry of one token. The returned
+// status is the HTTP status of the identity request, 0 if none was sent.
+func checkToken(t orgTarget, tok string) (tokenInfo, int, error) {
+	var info tokenInfo
+	switch t.Provider {
+	case "github":
+		resp, body, err := preflightGet(t.apiBase()+"/user", "Authorization", "token "+tok)
+		if err != nil {
+			return info, statusOf(resp), err
+		}
+		var user struct {
+			Login string `json:"login"`
+		}
+		if err := json.Unmarshal(body, &user); err != nil {
+			return info, resp.StatusCode, err
+		}
+		info.Login = user.Login
+		if h, ok := resp.Header["X-Oauth-Scopes"]; ok {
+			info.ScopesKnown = true
+			for _, s := range strings.Split(strings.Join(h, ","), ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					info.Scopes = append(info.Scopes, s)
+				}
+			}
+		}
+		if exp := resp.Header.Get("Github-Authentication-Token-Expiration"); exp != "" {
+			info.Expires, _ = time.Parse("2006-01-02 15:04:05 MST", exp)
+		}
+		return info, resp.StatusCode, nil
+	case "gitlab":
+		resp, body, err := preflightGet(t.apiBase()+"/user", "PRIVATE-TOKEN", tok)
+		if err != nil {
+			return info, statusOf(resp), err
+		}
+		var user struct {
+			Username string `json:"username"`
+		}
+		if err := json.Unmarshal(body, &user); err != nil {
+			return info, resp.StatusCode, err
+		}
+		info.Login = user.Username
+		// only personal, group and project access tokens can describe themselves
+		if _, body, err := preflightGet(t.apiBase()+"/personal_access_tokens/self", "PRIVATE-TOKEN", tok); err == nil {
+			var self struct {
+				Scopes    []string `json:"scopes"`
+				ExpiresAt string   `json:"expires_at"`
+			}
+			if json.Unmarshal(body, &self) == nil {
+				info.Scopes, info.ScopesKnown = self.Scopes, true
+				info.Expires, _ = time.Parse("2006-01-02", self.ExpiresAt)
+			}
+		}
+		return info, resp.StatusCode, nil
+	}
+	return info, 0, fmt.Errorf("token checks are not supported for %s", t.Provider)
+}
+
+func preflightGet(rawURL, header, value string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set(header, value)
+	return doRequest(req)
+}
+
+func statusOf(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+// tokenWarnings lists what the token cannot do for the requested mode
+func tokenWarnings(t orgTarget, info tokenInfo) []string {
+	var warnings []string
+	has := func(scopes ...string) bool {
+		for _, s := range info.Scopes {
+			for _, want := range scopes {
+				if s == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if info.ScopesKnown {
+		switch t.Provider {
+		case "github":
+			if !has("repo") {
+				warnings = append(warnings, "token lacks the repo scope: private repositories will be missing")
+			}
+			if wantMembers() && !has("read:org", "write:org", "admin:org") {
+				warnings = append(warnings, "token lacks the read:org scope: only public org members will be listed")
+			}
+		case "gitlab":
+			if !has("read_api", "api") {
+				warnings = append(warnings, "token lacks the read_api scope: groups, projects and members cannot be listed")
+			}
+		}
+	}
+	if !info.Expires.IsZero() {
+		if left := time.Until(info.Expires); left <= 0 {
+			warnings = append(warnings, fmt.Sprintf("token expired on %s", info.Expires.Format("2006-01-02")))
+		} else if left < tokenExpiryWarning {
+			warnings = append(warnings, fmt.Sprintf("token expires on %s (in %s)", info.Expires.Format("2006-01-02 15:04 MST"), formatDuration(left)))
+		}
+	}
+	return warnings
+}
+
+func formatDuration(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%d days", int(d.Hours()/24))
+	}
+	if d >= 2*time.Hour {
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d minutes", int(d.Minutes()))
+}
+
+// preflightTarget is one distinct token of one instance
+type preflightTarget struct {
+	orgTarget
+	tok   string
+	label string
+}
+
+// preflightTargets returns every distinct token of the GitHub and GitLab
+// targets; pool tokens are checked one by one, GitHub App tokens not at all
+func preflightTargets(orgs []orgTarget) []preflightTarget {
+	var out []preflightTarget
+	seen := map[string]bool{}
+	for _, t := range orgs {
+		if t.Provider != "github" && t.Provider != "gitlab" || t.Token == "" || strings.HasPrefix(t.Token, appTokenPrefix) {
+			continue
+		}
+		tokens := splitTokens(t.Token)
+		for i, tok := range tokens {
+			k := t.apiBase() + " " + tok
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			label := t.Provider + " " + t.host()
+			if len(tokens) > 1 {
+				label += fmt.Sprintf(" (token %d/%d)", i+1, len(tokens))
+			}
+			out = append(out, preflightTarget{t, tok, label})
+		}
+	}
+	return out
+}
+
+// preflight checks every token before fetching and prints warnings to stderr.
+// It returns an error only when a token is rejected outright.
+func preflight(orgs []orgTarget) error {
+	for _, p := range preflightTargets(orgs) {
+		info, status, err := checkToken(p.orgTarget, p.tok)
+		if status == http.StatusUnauthorized {
+			return fmt.Errorf("token for %s (%s) was rejected: %v", p.label, p.TokenSource, err)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not check the token for %s: %v\n", p.label, err)
+			continue
+		}
+		for _, w := range tokenWarnings(p.orgTarget, info) {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", p.label, w)
+		}
+	}
+	return nil
+}
+
+// printWhoami describes every token: the user, scopes and expiry, plus the
+// warnings the preflight would print for the current mode
+func printWhoami(w io.Writer, orgs []orgTarget) {
+	seen := map[string]bool{}
+	for _, t := range orgs {
+		var msg string
+		switch {
+		case strings.HasPrefix(t.Token, appTokenPrefix):
+			msg = t.TokenSource
+		case t.Token == "":
+			msg = "no token"
+		case t.Provider != "github" && t.Provider != "gitlab":
+			msg = "token checks are not supported for " + t.Provider
+		default:
+			continue
+		}
+		if k := t.Provider + " " + t.host() + ": " + msg; !seen[k] {
+			seen[k] = true
+			fmt.Fprintln(w, k)
+		}
+	}
+	for _, p := range preflightTargets(orgs) {
+		fmt.Fprintf(w, "%s\n", p.label)
+		fmt.Fprintf(w, "  Source: %s\n", p.TokenSource)
+		info, _, err := checkToken(p.orgTarget, p.tok)
+		if err != nil {
+			fmt.Fprintf(w, "  Error: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(w, "  User: %s\n", info.Login)
+		if info.ScopesKnown {
+			fmt.Fprintf(w, "  Scopes: %s\n", strings.Join(info.Scopes, ", "))
+		} else {
+			fmt.Fprintf(w, "  Scopes: not reported (fine-grained or OAuth token)\n")
+		}
+		if info.Expires.IsZero() {
+			fmt.Fprintf(w, "  Expires: no expiry reported\n")
+		} else {
+			fmt.Fprintf(w, "  Expires: %s\n", info.Expires.Format("2006-01-02 15:04 MST"))
+		}
+		for _, warning := range tokenWarnings(p.orgTarget, info) {
+			fmt.Fprintf(w, "  Warning: %s\n", warning)
+		}
+	}
+}
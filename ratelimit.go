@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRateLimitRetries is how many times a request is retried after waiting out a rate limit
+const maxRateLimitRetries = 3
+
+// apiLimiter bounds the number of in-flight API requests to --api-parallel and pauses
+// every request for a host/credential pair once its rate limit is exhausted.
+type apiLimiter struct {
+	once      sync.Once
+	slots     chan struct{}
+	mu        sync.Mutex
+	resume    map[string]time.Time // host/credential -> time requests may resume
+	remaining map[string]int       // host/credential -> last reported remaining requests
+	labels    map[string]string    // host/credential -> name used in messages, e.g. "token 2/3"
+}
+
+var limiter = apiLimiter{
+	resume:    make(map[string]time.Time),
+	remaining: make(map[string]int),
+	labels:    make(map[string]string),
+}
+
+// limiterKey identifies the rate limit bucket of a request: its host and credential
+func limiterKey(host, auth string) string {
+	return host + " " + auth
+}
+
+func (l *apiLimiter) acquire() {
+	l.once.Do(func() {
+		n := apiParallel
+		if n < 1 {
+			n = 1
+		}
+		l.slots = make(chan struct{}, n)
+	})
+	l.slots <- struct{}{}
+}
+
+func (l *apiLimiter) release() { <-l.slots }
+
+// wait blocks until the rate limit window for key has reset
+func (l *apiLimiter) wait(key string) {
+	l.mu.Lock()
+	until := l.resume[key]
+	l.mu.Unlock()
+	if d := time.Until(until); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// state returns the last reported remaining requests of key (ok is false when
+// none was reported yet) and the time its exhausted limit resets
+func (l *apiLimiter) state(key string) (remaining int, ok bool, resume time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	remaining, ok = l.remaining[key]
+	return remaining, ok, l.resume[key]
+}
+
+func (l *apiLimiter) label(key, name string) {
+	l.mu.Lock()
+	l.labels[key] = name
+	l.mu.Unlock()
+}
+
+// update records the rate limit state reported by resp and returns true when
+// the request was rejected because of it and should be retried.
+func (l *apiLimiter) update(key string, resp *http.Response) bool {
+	limited := resp.StatusCode == http.StatusTooManyRequests
+	var until time.Time
+	if s := firstHeader(resp.Header, "Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			until = time.Now().Add(time.Duration(secs) * time.Second)
+			limited = limited || resp.StatusCode == http.StatusForbidden
+		}
+	}
+	remaining := firstHeader(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining")
+	if n, err := strconv.Atoi(remaining); err == nil {
+		l.mu.Lock()
+		l.remaining[key] = n
+		l.mu.Unlock()
+	}
+	if remaining == "0" {
+		if secs, err := strconv.ParseInt(firstHeader(resp.Header, "X-RateLimit-Reset", "RateLimit-Reset"), 10, 64); err == nil {
+			if t := time.Unix(secs, 0); t.After(until) {
+				until = t
+			}
+		}
+		limited = limited || resp.StatusCode == http.StatusForbidden
+	}
+	if limited && until.IsZero() {
+		until = time.Now().Add(time.Minute)
+	}
+	if until.IsZero() {
+		return false
+	}
+	l.mu.Lock()
+	if until.After(l.resume[key]) {
+		l.resume[key] = until
+		host := resp.Request.URL.Host
+		if name := l.labels[key]; name != "" {
+			host += " (" + name + ")"
+		}
+		fmt.Fprintf(os.Stderr, "Rate limit reached for %s, pausing requests until %s\n", host, until.Format("15:04:05"))
+	}
+	l.mu.Unlock()
+	return limited
+}
+
+func firstHeader(h http.Header, names ...string) string {
+	for _, n := range names {
+		if v := h.Get(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// doRequest sends req through the limiter and returns the response with its body.
+// Rate-limited requests are retried once the limit resets; other non-2xx
+// responses are returned as errors.
+func doRequest(req *http.Request) (*http.Response, []byte, error) {
+	return doRequestAuth(req, nil)
+}
+
+// doRequestAuth is doRequest with authorize called before every attempt to set
+// the request's credentials, so that a request rejected by the rate limit of a
+// pool token is retried right away with another token that is not exhausted.
+// Switching to a token not tried yet does not count as a retry.
+func doRequestAuth(req *http.Request, authorize func(*http.Request) error) (*http.Response, []byte, error) {
+	tried := map[string]bool{}
+	for attempt := 0; ; {
+		if authorize != nil {
+			if err := authorize(req); err != nil {
+				return nil, nil, err
+			}
+		}
+		key := limiterKey(req.URL.Host, req.Header.Get("Authorization")+req.Header.Get("PRIVATE-TOKEN"))
+		if tried[key] {
+			attempt++
+		}
+		tried[key] = true
+		limiter.wait(key)
+		limiter.acquire()
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			limiter.release()
+			return nil, nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		limiter.release()
+		if err != nil {
+			return nil, nil, err
+		}
+		if limiter.update(key, resp) && attempt < maxRateLimitRetries {
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return resp, body, apiError(resp, body)
+		}
+		return resp, body, nil
+	}
+}
+
+// apiError turns a non-2xx response into an error, using the API's message when it has one
+func apiError(resp *http.Response, body []byte) error {
+	var msg struct {
+		Message interface{} `json:"message"`
+		Error   string      `json:"error"`
+	}
+	if json.Unmarshal(body, &msg) == nil {
+		if msg.Message != nil {
+			return fmt.Errorf("%s: %v", resp.Status, msg.Message)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, msg.Error)
+		}
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
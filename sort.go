@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sortKeys are the accepted values of --sort
+var sortKeys = []string{"name", "size", "owner", "pushed", "created"}
+
+func validateSort() error {
+	for _, k := range sortKeys {
+		if sortBy == k {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --sort %q (expected %s)", sortBy, strings.Join(sortKeys, ", "))
+}
+
+// repoLess orders repos by --sort, falling back to owner/name so the order is total.
+// --reverse flips the whole comparison.
+func repoLess(a, b RepoInfo) bool {
+	if sortReverse {
+		a, b = b, a
+	}
+	switch sortBy {
+	case "size":
+		if a.Size != b.Size {
+			return a.Size < b.Size
+		}
+	case "pushed":
+		if !a.Pushed.Equal(b.Pushed) {
+			return a.Pushed.Before(b.Pushed)
+		}
+	case "created":
+		if !a.Created.Equal(b.Created) {
+			return a.Created.Before(b.Created)
+		}
+	case "owner":
+		if !strings.EqualFold(a.Owner, b.Owner) {
+			return strings.ToLower(a.Owner) < strings.ToLower(b.Owner)
+		}
+	}
+	if !strings.EqualFold(a.Name, b.Name) {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	}
+	if a.Owner != b.Owner {
+		return a.Owner < b.Owner
+	}
+	return a.URL < b.URL
+}
+
+func sortRepos(repos []RepoInfo) {
+	sort.SliceStable(repos, func(i, j int) bool { return repoLess(repos[i], repos[j]) })
+}
+
+// sortData orders every repo and member list of the fetched model in place, so text,
+// JSON and CSV output all see the same order. Members are ordered by login.
+func sortData(data []*OrgData) {
+	for _, d := range data {
+		sortRepos(d.Repos)
+		sort.SliceStable(d.Members, func(i, j int) bool {
+			if sortReverse {
+				i, j = j, i
+			}
+			return strings.ToLower(d.Members[i].Login) < strings.ToLower(d.Members[j].Login)
+		})
+		sort.SliceStable(d.MemberRepos, func(i, j int) bool {
+			if sortReverse {
+				i, j = j, i
+			}
+			return strings.ToLower(d.MemberRepos[i].Member) < strings.ToLower(d.MemberRepos[j].Member)
+		})
+		for _, mr := range d.MemberRepos {
+			sortRepos(mr.Repos)
+		}
+	}
+}
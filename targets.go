@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// providers lists every supported --provider value
+var providers = []string{"github", "gitlab", "bitbucket", "gitea", "azure"}
+
+// orgTarget is one org/group to inventory, with the provider, instance and token to use for it
+type orgTarget struct {
+	Provider string
+	BaseURL  string // instance URL for self-hosted providers, "" for the public default
+	Token    string
+	// TokenSource says where Token came from, for reporting; never the value itself
+	TokenSource string
+	Name        string
+}
+
+// parseOrgLine parses one --orgname value or org file line. Accepted forms:
+//
+//	acme                              uses --provider, --base-url and --token
+//	github:acme                       provider prefix (--token only if it is --provider)
+//	gitlab@https://git.corp:platform  provider with instance URL (never --token)
+//	gitea:team token=env:GITEA_TOKEN  per-line token reference (env:NAME or file:PATH)
+func parseOrgLine(line string) (orgTarget, error) {
+	t := orgTarget{Provider: provider, BaseURL: baseURL, Token: token, TokenSource: tokenSource}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return t, fmt.Errorf("empty org entry")
+	}
+	spec := fields[0]
+	if i := strings.IndexAny(spec, ":@"); i > 0 && isProvider(spec[:i]) {
+		t.Provider = spec[:i]
+		rest := spec[i+1:]
+		if spec[i] == '@' {
+			// the instance URL contains colons itself, so the name follows the last one
+			j := strings.LastIndex(rest, ":")
+			if j < 0 || strings.HasPrefix(rest[j:], "://") {
+				return t, fmt.Errorf("%q: expected provider@URL:name", spec)
+			}
+			t.BaseURL, rest = strings.TrimRight(rest[:j], "/"), rest[j+1:]
+		} else if t.Provider != provider {
+			// the --base-url belongs to the --provider, not to a different prefixed provider
+			t.BaseURL = ""
+		}
+		if spec[i] == '@' || t.Provider != provider {
+			// neither is --token: never send it to another provider or host;
+			// a token= option or resolveTokens fills it in
+			t.Token, t.TokenSource = "", ""
+		}
+		spec = rest
+	} else if i > 0 && spec[i] == ':' && !strings.Contains(spec[:i], "/") {
+		return t, fmt.Errorf("%q: unknown provider %q", spec, spec[:i])
+	}
+	if spec == "" {
+		return t, fmt.Errorf("%q: missing org name", fields[0])
+	}
+	t.Name = spec
+	for _, opt := range fields[1:] {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "token":
+			tok, err := tokenRef(value)
+			if err != nil {
+				return t, fmt.Errorf("%q: %v", fields[0], err)
+			}
+			t.Token, t.TokenSource = tok, opt
+		default:
+			return t, fmt.Errorf("%q: unknown option %q", fields[0], opt)
+		}
+	}
+	return t, nil
+}
+
+// tokenRef resolves a per-line token reference. Tokens are never written into
+// org files directly, only pointed at. A file may hold several tokens, one per
+// line, which are returned as a comma-separated token pool.
+func tokenRef(ref string) (string, error) {
+	kind, arg, _ := strings.Cut(ref, ":")
+	switch kind {
+	case "env":
+		v := os.Getenv(arg)
+		if v == "" {
+			return "", fmt.Errorf("environment variable %s is not set", arg)
+		}
+		return v, nil
+	case "file":
+		b, err := os.ReadFile(arg)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(strings.Fields(string(b)), ","), nil
+	}
+	return "", fmt.Errorf("unsupported token reference %q (expected env:NAME or file:PATH)", ref)
+}
+
+func isProvider(name string) bool {
+	for _, p := range providers {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTarget checks the provider-specific requirements of a target
+func validateTarget(t orgTarget) error {
+	if t.Provider != "github" && len(splitTokens(t.Token)) > 1 {
+		return fmt.Errorf("%s: multiple tokens are only supported for GitHub", t.Name)
+	}
+	switch t.Provider {
+	case "github", "gitlab":
+	case "gitea":
+		if t.BaseURL == "" {
+			return fmt.Errorf("%s: gitea needs a base URL (--base-url or gitea@URL:name)", t.Name)
+		}
+	case "bitbucket", "azure":
+		if t.BaseURL != "" {
+			return fmt.Errorf("%s: %s only supports the cloud service, not a base URL", t.Name, t.Provider)
+		}
+		if t.Provider == "azure" && repoType != "org" {
+			return fmt.Errorf("%s: Azure DevOps has no user-owned repositories; only --repo-type org is supported", t.Name)
+		}
+	default:
+		return fmt.Errorf("%s: unknown provider %q (expected %s)", t.Name, t.Provider, strings.Join(providers, ", "))
+	}
+	return nil
+}
+
+// expandAzureOrgs replaces an azure target named "*" with one target per
+// Azure DevOps organization the token's user belongs to
+func expandAzureOrgs(orgs []orgTarget) ([]orgTarget, error) {
+	var out []orgTarget
+	for _, t := range orgs {
+		if t.Provider != "azure" || t.Name != "*" {
+			out = append(out, t)
+			continue
+		}
+		names, err := fetchAzureOrganizations(t.Token)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			e := t
+			e.Name = name
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// label prefixes name with the target's provider and instance when they differ from
+// --provider/--base-url, using the same syntax as the org file
+func (t orgTarget) label(name string) string {
+	switch {
+	case t.BaseURL != baseURL && t.BaseURL != "":
+		return t.Provider + "@" + t.BaseURL + ":" + name
+	case t.Provider != provider:
+		return t.Provider + ":" + name
+	}
+	return name
+}
+
+// apiBase returns the REST API root of the target's instance
+func (t orgTarget) apiBase() string {
+	switch t.Provider {
+	case "github":
+		if t.BaseURL != "" {
+			return t.BaseURL + "/api/v3" // GitHub Enterprise Server
+		}
+		return "https://api.github.com"
+	case "gitlab":
+		if t.BaseURL != "" {
+			return t.BaseURL + "/api/v4"
+		}
+		return "https://gitlab.com/api/v4"
+	}
+	return t.BaseURL
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestParseOrgLine(t *testing.T) {
+	provider, baseURL, token, tokenSource = "github", "", "GH-SECRET", "--token"
+	t.Setenv("CORP_TOKEN", "corp-secret")
+	defer func() { provider, baseURL, token, tokenSource = "github", "", "", "" }()
+
+	tests := []struct {
+		line string
+		want orgTarget
+	}{
+		{"acme", orgTarget{Provider: "github", Token: "GH-SECRET", TokenSource: "--token", Name: "acme"}},
+		{"github:acme", orgTarget{Provider: "github", Token: "GH-SECRET", TokenSource: "--token", Name: "acme"}},
+		{"gitlab:acme", orgTarget{Provider: "gitlab", Name: "acme"}},
+		{"gitlab@https://git.corp:platform", orgTarget{Provider: "gitlab", BaseURL: "https://git.corp", Name: "platform"}},
+		{"gitlab@http://127.0.0.1:18777:grp", orgTarget{Provider: "gitlab", BaseURL: "http://127.0.0.1:18777", Name: "grp"}},
+		{"github@https://ghe.corp/:acme", orgTarget{Provider: "github", BaseURL: "https://ghe.corp", Name: "acme"}},
+		{"gitlab:acme/platform token=env:CORP_TOKEN", orgTarget{Provider: "gitlab", Token: "corp-secret", TokenSource: "token=env:CORP_TOKEN", Name: "acme/platform"}},
+		{"gitea@https://gitea.corp:tools  token=env:CORP_TOKEN", orgTarget{Provider: "gitea", BaseURL: "https://gitea.corp", Token: "corp-secret", TokenSource: "token=env:CORP_TOKEN", Name: "tools"}},
+	}
+	for _, tt := range tests {
+		got, err := parseOrgLine(tt.line)
+		if err != nil {
+			t.Errorf("parseOrgLine(%q): %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseOrgLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{"", "bogus:acme", "gitlab@https://git.corp", "gitlab:", "acme token=env:ORGFETCH_UNSET_VAR", "acme colour=red"} {
+		if _, err := parseOrgLine(line); err == nil {
+			t.Errorf("parseOrgLine(%q): expected an error", line)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenStrategies lists the --token-strategy values
+var tokenStrategies = []string{"round-robin", "most-remaining"}
+
+// tokenPool spreads the requests for one token list over its tokens. A token
+// whose rate limit is exhausted leaves the rotation until the limit resets.
+type tokenPool struct {
+	tokens []string
+	mu     sync.Mutex
+	next   int
+}
+
+var (
+	poolsMu sync.Mutex
+	pools   = map[string]*tokenPool{}
+)
+
+// splitTokens splits a comma-separated token list, as accepted by --token
+func splitTokens(s string) []string {
+	var tokens []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// tokenPoolFor returns the shared pool of a token list
+func tokenPoolFor(tokens string) *tokenPool {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	p := pools[tokens]
+	if p == nil {
+		p = &tokenPool{tokens: splitTokens(tokens)}
+		pools[tokens] = p
+	}
+	return p
+}
+
+// pick returns the token to use for the next request to host. auth formats a
+// token as its Authorization header, which together with the host identifies
+// the token's rate limit in the limiter. When every token is exhausted it
+// returns the one that resets first, and the limiter waits for it.
+func (p *tokenPool) pick(host string, auth func(string) string) string {
+	switch len(p.tokens) {
+	case 0:
+		return ""
+	case 1:
+		return p.tokens[0]
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	best, bestRemaining := -1, 0
+	soonest, soonestAt := 0, time.Time{}
+	for k := range p.tokens {
+		i := (p.next + k) % len(p.tokens)
+		key := limiterKey(host, auth(p.tokens[i]))
+		limiter.label(key, fmt.Sprintf("token %d/%d", i+1, len(p.tokens)))
+		remaining, ok, resume := limiter.state(key)
+		if resume.After(now) {
+			if soonestAt.IsZero() || resume.Before(soonestAt) {
+				soonest, soonestAt = i, resume
+			}
+			continue
+		}
+		if tokenStrategy == "round-robin" {
+			best = i
+			break
+		}
+		if !ok {
+			// not used yet, so it has its full limit
+			remaining = math.MaxInt
+		}
+		if best < 0 || remaining > bestRemaining {
+			best, bestRemaining = i, remaining
+		}
+	}
+	if best < 0 {
+		best = soonest
+	}
+	p.next = (best + 1) % len(p.tokens)
+	return p.tokens[best]
+}
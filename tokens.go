@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resolveFlagToken reads the token from --token-file or --token-cmd. Only one of
+// --token, --token-file and --token-cmd may be given.
+func resolveFlagToken() error {
+	set := 0
+	for _, s := range []string{token, tokenFile, tokenCmd} {
+		if s != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("use only one of --token, --token-file and --token-cmd")
+	}
+	switch {
+	case token != "":
+		if tokenSource == "" {
+			tokenSource = "--token"
+		}
+	case tokenFile != "":
+		tok, err := tokenRef("file:" + tokenFile)
+		if err != nil {
+			return fmt.Errorf("--token-file: %v", err)
+		}
+		token, tokenSource = tok, "--token-file "+tokenFile
+	case tokenCmd != "":
+		tok, err := runTokenCmd(tokenCmd)
+		if err != nil {
+			return fmt.Errorf("--token-cmd: %v", err)
+		}
+		token, tokenSource = tok, "--token-cmd"
+	}
+	return nil
+}
+
+// runTokenCmd runs an external command (a password manager, a vault CLI, ...)
+// through the shell and returns the first line of its output
+func runTokenCmd(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	// stderr stays attached so the command can report errors; stdin is not
+	// passed on, as it may carry the org list (--orgname -)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	tok, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	tok = strings.TrimSpace(tok)
+	if tok == "" {
+		return "", fmt.Errorf("command printed no token")
+	}
+	return tok, nil
+}
+
+// resolveTokens fills in the token of every target that has none from --token,
+// --token-file, --token-cmd or its org entry, trying in order the environment,
+// ~/.netrc and the gh/glab CLI config files. It then reports on stderr which
+// source each provider/instance uses; token values are never printed.
+func resolveTokens(orgs []orgTarget) {
+	sources := map[string]string{}
+	for i := range orgs {
+		t := &orgs[i]
+		if t.Token == "" {
+			t.Token, t.TokenSource = lookupToken(*t)
+		}
+		src := t.TokenSource
+		if t.Token == "" {
+			src = "none found, sending unauthenticated requests"
+		}
+		sources[t.Provider+" "+t.host()] = src
+	}
+	keys := make([]string, 0, len(sources))
+	for k := range sources {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(os.Stderr, "Token for %s: %s\n", k, sources[k])
+	}
+}
+
+// lookupToken finds a token for t in the environment, ~/.netrc or the CLI config
+// files and returns it with a description of where it came from. The
+// environment variables are only used for the public instances: a token for
+// github.com must not be sent to a self-hosted instance.
+func lookupToken(t orgTarget) (string, string) {
+	if t.BaseURL == "" {
+		var envVars []string
+		switch t.Provider {
+		case "github":
+			envVars = append(envVars, "GITHUB_TOKEN")
+		case "gitlab":
+			envVars = append(envVars, "GITLAB_TOKEN")
+		}
+		for _, name := range append(envVars, "ORGFETCH_TOKEN") {
+			if v := os.Getenv(name); v != "" {
+				return v, "$" + name
+			}
+		}
+	}
+	if tok, path := netrcToken(t); tok != "" {
+		return tok, path
+	}
+	switch t.Provider {
+	case "github":
+		if tok, path := ghToken(t.host()); tok != "" {
+			return tok, path
+		}
+	case "gitlab":
+		if tok, path := glabToken(t.host()); tok != "" {
+			return tok, path
+		}
+	}
+	return "", ""
+}
+
+// host returns the web host name of the target's instance
+func (t orgTarget) host() string {
+	if t.BaseURL != "" {
+		if u, err := url.Parse(t.BaseURL); err == nil && u.Host != "" {
+			return u.Host
+		}
+		return t.BaseURL
+	}
+	switch t.Provider {
+	case "github":
+		return "github.com"
+	case "gitlab":
+		return "gitlab.com"
+	case "bitbucket":
+		return "bitbucket.org"
+	case "azure":
+		return "dev.azure.com"
+	}
+	return ""
+}
+
+// netrcToken looks up the password of the target's host (or its API host) in
+// $NETRC or ~/.netrc. Bitbucket entries are returned as login:password for
+// app-password auth.
+func netrcToken(t orgTarget) (string, string) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", ""
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", ""
+	}
+	hosts := []string{t.host()}
+	switch {
+	case t.Provider == "github" && t.BaseURL == "":
+		hosts = append(hosts, "api.github.com")
+	case t.Provider == "bitbucket":
+		hosts = append(hosts, "api.bitbucket.org")
+	}
+	entries := parseNetrc(b)
+	for _, h := range hosts {
+		if e, ok := entries[h]; ok && e.password != "" {
+			if t.Provider == "bitbucket" && e.login != "" {
+				return e.login + ":" + e.password, path
+			}
+			return e.password, path
+		}
+	}
+	return "", ""
+}
+
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+// parseNetrc returns the login/password of every machine entry in a netrc file
+func parseNetrc(b []byte) map[string]netrcEntry {
+	entries := map[string]netrcEntry{}
+	fields := strings.Fields(string(b))
+	machine := ""
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if i+1 < len(fields) {
+				i++
+				machine = fields[i]
+			}
+		case "default":
+			machine = ""
+		case "login", "password":
+			if i+1 < len(fields) && machine != "" {
+				e := entries[machine]
+				if fields[i] == "login" {
+					e.login = fields[i+1]
+				} else {
+					e.password = fields[i+1]
+				}
+				entries[machine] = e
+			}
+			i++
+		}
+	}
+	return entries
+}
+
+// cliConfigDir returns the config directory of the gh or glab CLI
+func cliConfigDir(env, name string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, name)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", name)
+}
+
+// ghToken reads the oauth_token of host from the gh CLI's hosts.yml. Tokens gh
+// keeps in the system keyring are not visible there.
+func ghToken(host string) (string, string) {
+	path := filepath.Join(cliConfigDir("GH_CONFIG_DIR", "gh"), "hosts.yml")
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if !readYAML(path, &hosts) {
+		return "", ""
+	}
+	return hosts[host].OAuthToken, path
+}
+
+// glabToken reads the token of host from the glab CLI's config.yml
+func glabToken(host string) (string, string) {
+	path := filepath.Join(cliConfigDir("GLAB_CONFIG_DIR", "glab-cli"), "config.yml")
+	var cfg struct {
+		Hosts map[string]struct {
+			Token string `yaml:"token"`
+		} `yaml:"hosts"`
+	}
+	if !readYAML(path, &cfg) {
+		return "", ""
+	}
+	return cfg.Hosts[host].Token, path
+}
+
+func readYAML(path string, v interface{}) bool {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return yaml.Unmarshal(b, v) == nil
+}
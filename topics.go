@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// topicMatches lists the accepted --topic-match values
+var topicMatches = []string{"any", "all"}
+
+// topicsSupported reports whether the provider reports repo topics (GitLab calls them tags)
+func topicsSupported(provider string) bool {
+	return provider == "github" || provider == "gitlab" || provider == "gitea"
+}
+
+// matchTopics reports whether the repo has any (or, with --topic-match all,
+// every) --topic value; topics are compared case-insensitively
+func matchTopics(topics []string) bool {
+	has := func(want string) bool {
+		for _, t := range topics {
+			if strings.EqualFold(t, strings.TrimSpace(want)) {
+				return true
+			}
+		}
+		return false
+	}
+	all := topicMatch == "all"
+	for _, want := range topicFilter {
+		if has(want) != all {
+			// any: one match is enough; all: one miss is too many
+			return !all
+		}
+	}
+	return all
+}
+
+// topicCount is one line of the topics report
+type topicCount struct {
+	Topic string   `json:"topic"`
+	Repos int      `json:"repos"`
+	Orgs  []string `json:"orgs"`
+}
+
+// topicInventory is the topics report: every topic of the listed repos, most used first
+type topicInventory struct {
+	Repos    int          `json:"repos"`
+	Untagged int          `json:"untagged"` // repos without any topic
+	Topics   []topicCount `json:"topics"`
+}
+
+// inventoryTopics counts the topics of the repos that pass the filters. Topics
+// differing only in case are counted together under the first spelling seen.
+func inventoryTopics(data []*OrgData) topicInventory {
+	var inv topicInventory
+	counts := map[string]*topicCount{}
+	for _, d := range data {
+		if d.RepoErr != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching repos for %s: %v\n", d.Name, d.RepoErr)
+		}
+		var repos []RepoInfo
+		if repoType != "member" {
+			repos = filterRepos(d.Repos)
+		}
+		for _, mr := range d.MemberRepos {
+			if mr.Err != nil {
+				fmt.Fprintf(os.Stderr, "Error fetching repos for %s: %v\n", mr.Member, mr.Err)
+				continue
+			}
+			repos = append(repos, filterRepos(mr.Repos)...)
+		}
+		org := d.Target.label(d.Name)
+		for _, r := range repos {
+			inv.Repos++
+			if len(r.Topics) == 0 {
+				inv.Untagged++
+			}
+			seen := map[string]bool{}
+			for _, topic := range r.Topics {
+				// case-insensitive like --topic; GitLab tags are not normalized
+				key := strings.ToLower(topic)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				c := counts[key]
+				if c == nil {
+					c = &topicCount{Topic: topic}
+					counts[key] = c
+				}
+				c.Repos++
+				if !contains(c.Orgs, org) {
+					c.Orgs = append(c.Orgs, org)
+				}
+			}
+		}
+	}
+	inv.Topics = []topicCount{}
+	for _, c := range counts {
+		inv.Topics = append(inv.Topics, *c)
+	}
+	sort.Slice(inv.Topics, func(i, j int) bool {
+		if inv.Topics[i].Repos != inv.Topics[j].Repos {
+			return inv.Topics[i].Repos > inv.Topics[j].Repos
+		}
+		return inv.Topics[i].Topic < inv.Topics[j].Topic
+	})
+	return inv
+}
+
+// printTopics writes the topics report in the selected format
+func printTopics(w io.Writer, data []*OrgData) reportTotals {
+	inv := inventoryTopics(data)
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(inv); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+		}
+	case "csv":
+		cw := csv.NewWriter(w)
+		defer cw.Flush()
+		cw.Write([]string{"topic", "repos", "orgs"})
+		for _, c := range inv.Topics {
+			cw.Write([]string{c.Topic, strconv.Itoa(c.Repos), strings.Join(c.Orgs, ";")})
+		}
+	default:
+		fmt.Fprintf(w, "Topics of %d repos (%d without topics):\n", inv.Repos, inv.Untagged)
+		width := 0
+		for _, c := range inv.Topics {
+			width = max(width, len(c.Topic))
+		}
+		for _, c := range inv.Topics {
+			fmt.Fprintf(w, "  %-*s  %4d  %s\n", width, c.Topic, c.Repos, strings.Join(c.Orgs, ", "))
+		}
+	}
+	return reportTotals{Repos: inv.Repos}
+}
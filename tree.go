@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GroupInfo is one group of a GitLab hierarchy with its direct project and member counts
+type GroupInfo struct {
+	Path     string       `json:"path"`
+	Projects int          `json:"projects"`
+	Members  int          `json:"members"`
+	id       string       // API id used for follow-up requests
+	members  []MemberInfo // direct members, merged for --recursive member listings
+}
+
+// fetchGroupTree returns the group itself followed by all its descendant groups, ordered by path.
+// Direct members of every group are fetched when withMembers is set.
+func fetchGroupTree(t orgTarget, path, id string, withMembers bool) ([]GroupInfo, error) {
+	subgroups, err := fetchGitLabDescendantGroups(t.apiBase(), t.Token, id)
+	if err != nil {
+		return nil, err
+	}
+	groups := []GroupInfo{{Path: path, id: id}}
+	for _, g := range subgroups {
+		groups = append(groups, GroupInfo{Path: g.FullPath, id: strconv.Itoa(g.ID)})
+	}
+	sort.SliceStable(groups[1:], func(i, j int) bool { return groups[i+1].Path < groups[j+1].Path })
+	if !withMembers {
+		return groups, nil
+	}
+	errs := make([]error, len(groups))
+	forEach(len(groups), func(i int) {
+		members, err := fetchGitLabMembers(t.apiBase(), t.Token, groups[i].id)
+		for _, m := range members {
+			groups[i].members = append(groups[i].members, m.info())
+		}
+		groups[i].Members = len(members)
+		errs[i] = err
+	})
+	for i, err := range errs {
+		if err != nil {
+			return groups, fmt.Errorf("members of %s: %v", groups[i].Path, err)
+		}
+	}
+	return groups, nil
+}
+
+// mergeGroupMembers returns the members of the whole hierarchy, each login once
+// with the highest access level (and its expiry) of all the groups they belong to
+func mergeGroupMembers(groups []GroupInfo) []MemberInfo {
+	var out []MemberInfo
+	index := map[string]int{}
+	for _, g := range groups {
+		for _, m := range g.members {
+			i, seen := index[m.Login]
+			if !seen {
+				index[m.Login] = len(out)
+				out = append(out, m)
+			} else if m.level > out[i].level {
+				out[i] = m
+			}
+		}
+	}
+	return out
+}
+
+// countGroupProjects sets the direct project count of every group from the projects' namespaces
+func countGroupProjects(groups []GroupInfo, repos []RepoInfo) {
+	repos = filterRepos(repos)
+	for i := range groups {
+		groups[i].Projects = 0
+		for _, r := range repos {
+			if r.Owner == groups[i].Path {
+				groups[i].Projects++
+			}
+		}
+	}
+}
+
+// printTree draws the group hierarchy with direct counts, plus the subtree project total for parents
+func printTree(w io.Writer, d *OrgData) {
+	if d.GroupErr != nil {
+		fmt.Fprintf(w, "Error fetching groups: %v\n", d.GroupErr)
+	}
+	if len(d.Groups) == 0 {
+		return
+	}
+	root := d.Groups[0]
+	known := map[string]bool{}
+	for _, g := range d.Groups[1:] {
+		known[g.Path] = true
+	}
+	// subgroups whose parent is not a known subgroup hang off the root
+	children := map[string][]GroupInfo{}
+	for _, g := range d.Groups[1:] {
+		parent := root.Path
+		if i := strings.LastIndex(g.Path, "/"); i > 0 && known[g.Path[:i]] {
+			parent = g.Path[:i]
+		}
+		children[parent] = append(children[parent], g)
+	}
+	var subtree func(path string) int
+	subtree = func(path string) int {
+		n := 0
+		for _, c := range children[path] {
+			n += c.Projects + subtree(c.Path)
+		}
+		return n
+	}
+	label := func(g GroupInfo) string {
+		s := fmt.Sprintf("%s (%d projects, %d members", g.Path, g.Projects, g.Members)
+		if sub := subtree(g.Path); sub > 0 {
+			s += fmt.Sprintf(", %d projects incl. subgroups", g.Projects+sub)
+		}
+		return s + ")"
+	}
+	var walk func(path, indent string)
+	walk = func(path, indent string) {
+		kids := children[path]
+		for i, c := range kids {
+			branch, next := "├── ", "│   "
+			if i == len(kids)-1 {
+				branch, next = "└── ", "    "
+			}
+			fmt.Fprintf(w, "%s%s%s\n", indent, branch, label(c))
+			walk(c.Path, indent+next)
+		}
+	}
+	fmt.Fprintf(w, "%s [ID %s]\n", label(root), d.ID)
+	walk(root.Path, "")
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestMergeGroupMembersKeepsHighestRole(t *testing.T) {
+	member := func(login string, level int, expires string) MemberInfo {
+		return GitLabMember{Username: login, AccessLevel: level, ExpiresAt: expires}.info()
+	}
+	groups := []GroupInfo{
+		{Path: "acme", members: []MemberInfo{member("dana", 10, ""), member("erin", 50, "")}},
+		{Path: "acme/plat", members: []MemberInfo{member("erin", 30, "")}},
+		{Path: "acme/plat/infra", members: []MemberInfo{member("dana", 40, "2027-01-31"), member("finn", 20, "")}},
+	}
+	got := mergeGroupMembers(groups)
+	want := []MemberInfo{
+		{Login: "dana", Role: "Maintainer", Expires: "2027-01-31", level: 40},
+		{Login: "erin", Role: "Owner", level: 50},
+		{Login: "finn", Role: "Reporter", level: 20},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("mergeGroupMembers = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("member %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	roleFilter = []string{"maintainer"}
+	defer func() { roleFilter = nil }()
+	if kept := filterMembers(got); len(kept) != 1 || kept[0].Login != "dana" {
+		t.Errorf("--role maintainer kept %+v, want dana only", kept)
+	}
+}
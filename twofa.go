@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errNoAdmin2FA is returned when a GitLab token cannot see two_factor_enabled
+var errNoAdmin2FA = errors.New("GitLab only reports two_factor_enabled to administrators; --2fa-disabled needs an admin token")
+
+// twoFASupported reports whether the provider can list members without 2FA
+func twoFASupported(provider string) bool {
+	return provider == "github" || provider == "gitlab"
+}
+
+// fetchNo2FAMembers returns the members of an org/group without two-factor
+// authentication. GitHub filters server-side; on GitLab every member is looked
+// up, which needs an admin token. groups is the --recursive hierarchy, if any.
+func fetchNo2FAMembers(t orgTarget, ref string, groups []GroupInfo, groupErr error) ([]MemberInfo, error) {
+	switch t.Provider {
+	case "github":
+		members, err := fetchMembers(t.apiBase(), t.Token, ref, "2fa_disabled")
+		var out []MemberInfo
+		for _, m := range members {
+			out = append(out, MemberInfo{Login: m.Login, Role: m.Role})
+		}
+		return out, err
+	case "gitlab":
+		members, err := mergeGroupMembers(groups), groupErr
+		if groups == nil {
+			members, err = fetchOrgMembers(t, ref)
+		}
+		if err != nil {
+			return nil, err
+		}
+		enabled := make([]*bool, len(members))
+		errs := make([]error, len(members))
+		forEach(len(members), func(i int) {
+			enabled[i], errs[i] = fetchGitLabTwoFactor(t.apiBase(), t.Token, members[i].Login)
+		})
+		var out []MemberInfo
+		for i, m := range members {
+			switch {
+			case errs[i] != nil:
+				return nil, fmt.Errorf("checking 2FA of %s: %v", m.Login, errs[i])
+			case enabled[i] == nil:
+				return nil, errNoAdmin2FA
+			case !*enabled[i]:
+				out = append(out, m)
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("--2fa-disabled is not supported for %s", t.Provider)
+}
+
+// no2FAReport is the JSON shape of one org in the --2fa-disabled report
+type no2FAReport struct {
+	Provider string       `json:"provider"`
+	BaseURL  string       `json:"base_url,omitempty"`
+	Org      string       `json:"org"`
+	Count    int          `json:"count"`
+	Members  []MemberInfo `json:"members"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// printNo2FA writes the members without 2FA of every org with a per-org count.
+// With --usernames-only the text report is just the logins, one per line.
+func printNo2FA(w io.Writer, data []*OrgData) reportTotals {
+	var t reportTotals
+	switch format {
+	case "json":
+		reports := []no2FAReport{}
+		for _, d := range data {
+			r := no2FAReport{Provider: d.Target.Provider, BaseURL: d.Target.BaseURL, Org: d.Name, Count: len(d.Members), Members: d.Members}
+			if r.Members == nil {
+				r.Members = []MemberInfo{}
+			}
+			if d.MemberErr != nil {
+				r.Error = d.MemberErr.Error()
+			}
+			reports = append(reports, r)
+			t.Members += len(d.Members)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+		}
+	case "csv":
+		cw := csv.NewWriter(w)
+		defer cw.Flush()
+		cw.Write([]string{"provider", "org", "login", "role", "expires_at"})
+		for _, d := range data {
+			if d.MemberErr != nil {
+				fmt.Fprintf(os.Stderr, "Error checking 2FA for %s: %v\n", d.Name, d.MemberErr)
+				continue
+			}
+			for _, m := range d.Members {
+				cw.Write([]string{d.Target.Provider, d.Name, m.Login, m.Role, m.Expires})
+			}
+			t.Members += len(d.Members)
+		}
+	default:
+		for _, d := range data {
+			if d.MemberErr != nil {
+				fmt.Fprintf(w, "Error checking 2FA for %s: %v\n", d.label(), d.MemberErr)
+				continue
+			}
+			if !usernamesOnly {
+				fmt.Fprintf(w, "Members without 2FA in %s: %d\n", d.label(), len(d.Members))
+			}
+			for _, m := range d.Members {
+				if usernamesOnly {
+					fmt.Fprintln(w, m.Login)
+				} else {
+					fmt.Fprintf(w, "  %s\n", memberLabel(m))
+				}
+			}
+			t.Members += len(d.Members)
+		}
+	}
+	return t
+}
+
+// no2FAFailed reports whether the compliance check fails: a member without
+// 2FA was found, or an org could not be checked
+func no2FAFailed(data []*OrgData) bool {
+	for _, d := range data {
+		if d.MemberErr != nil || len(d.Members) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// whereFilter is the compiled --where expression, set by validateFilters
+var whereFilter *whereExpr
+
+// whereExpr is a compiled --where expression: a boolean over the repo model
+type whereExpr struct {
+	eval   func(RepoInfo) exprValue
+	fields map[string]bool // fields the expression reads
+}
+
+// match reports whether the repo satisfies the expression
+func (w *whereExpr) match(r RepoInfo) bool {
+	return w.eval(r).b
+}
+
+// uses reports whether the expression reads the field
+func (w *whereExpr) uses(field string) bool {
+	return w != nil && w.fields[field]
+}
+
+type exprType int
+
+const (
+	typeBool exprType = iota
+	typeNumber
+	typeString
+	typeTime
+	typeList
+)
+
+func (t exprType) String() string {
+	return [...]string{"boolean", "number", "string", "date", "list"}[t]
+}
+
+// exprValue holds the value of a node; only the field of the node's type is set
+type exprValue struct {
+	b bool
+	n float64
+	s string
+	t time.Time
+	l []string
+}
+
+// whereFields maps the field names usable in --where to their type and accessor
+var whereFields = map[string]struct {
+	typ exprType
+	get func(RepoInfo) exprValue
+}{
+	"name":           {typeString, func(r RepoInfo) exprValue { return exprValue{s: r.Name} }},
+	"owner":          {typeString, func(r RepoInfo) exprValue { return exprValue{s: r.Owner} }},
+	"full_name":      {typeString, func(r RepoInfo) exprValue { return exprValue{s: r.Owner + "/" + r.Name} }},
+	"url":            {typeString, func(r RepoInfo) exprValue { return exprValue{s: r.URL} }},
+	"description":    {typeString, func(r RepoInfo) exprValue { return exprValue{s: r.Description} }},
+	"visibility":     {typeString, func(r RepoInfo) exprValue { return exprValue{s: r.Visibility} }},
+	"default_branch": {typeString, func(r RepoInfo) exprValue { return exprValue{s: r.DefaultBranch} }},
+	"language":       {typeString, func(r RepoInfo) exprValue { return exprValue{s: r.Language} }},
+	"license":        {typeString, func(r RepoInfo) exprValue { return exprValue{s: r.License} }},
+	"archived":       {typeBool, func(r RepoInfo) exprValue { return exprValue{b: r.Archived} }},
+	"disabled":       {typeBool, func(r RepoInfo) exprValue { return exprValue{b: r.Disabled} }},
+	"fork":           {typeBool, func(r RepoInfo) exprValue { return exprValue{b: r.Fork} }},
+	"size":           {typeNumber, func(r RepoInfo) exprValue { return exprValue{n: float64(r.Size)} }},
+	"stars":          {typeNumber, func(r RepoInfo) exprValue { return exprValue{n: float64(r.Stars)} }},
+	"forks":          {typeNumber, func(r RepoInfo) exprValue { return exprValue{n: float64(r.Forks)} }},
+	"open_issues":    {typeNumber, func(r RepoInfo) exprValue { return exprValue{n: float64(r.OpenIssues)} }},
+	"pushed":         {typeTime, func(r RepoInfo) exprValue { return exprValue{t: r.Pushed} }},
+	"created":        {typeTime, func(r RepoInfo) exprValue { return exprValue{t: r.Created} }},
+	"updated":        {typeTime, func(r RepoInfo) exprValue { return exprValue{t: r.Updated} }},
+	"topics":         {typeList, func(r RepoInfo) exprValue { return exprValue{l: r.Topics} }},
+	"languages": {typeList, func(r RepoInfo) exprValue {
+		var names []string
+		for _, s := range r.Languages {
+			names = append(names, s.Name)
+		}
+		return exprValue{l: names}
+	}},
+}
+
+// sizeUnits are the suffixes accepted on number literals, as in size < 50MB
+var sizeUnits = map[string]float64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}
+
+// exprNode is a type-checked node; lit is set for literals, so that a string
+// literal compared with a date field can be parsed as a date up front
+type exprNode struct {
+	typ  exprType
+	eval func(RepoInfo) exprValue
+	lit  *exprValue
+}
+
+type exprToken struct {
+	kind string // "ident", "number", "string", "eof" or the operator itself
+	text string
+	val  exprValue
+	pos  int // 1-based column
+}
+
+// whereError is a --where syntax or type error at a column of the expression
+type whereError struct {
+	pos int
+	msg string
+}
+
+func (e *whereError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.pos, e.msg)
+}
+
+// parseWhere compiles a --where expression, checking field names and types
+func parseWhere(src string) (*whereExpr, error) {
+	tokens, err := lexWhere(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &whereParser{tokens: tokens, fields: map[string]bool{}}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, &whereError{tok.pos, fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	if n.typ != typeBool {
+		return nil, &whereError{1, fmt.Sprintf("expression is a %s, expected a condition such as size < 50MB", n.typ)}
+	}
+	return &whereExpr{eval: n.eval, fields: p.fields}, nil
+}
+
+func lexWhere(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		start := i
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != byte(c) {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, &whereError{start + 1, "unterminated string"}
+			}
+			s := src[i+1 : j] // single quotes take the text as is
+			if c == '"' {
+				var err error
+				if s, err = strconv.Unquote(src[i : j+1]); err != nil {
+					return nil, &whereError{start + 1, "invalid string " + src[i:j+1]}
+				}
+			}
+			tokens = append(tokens, exprToken{kind: "string", text: src[i : j+1], val: exprValue{s: s}, pos: start + 1})
+			i = j + 1
+			continue
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, &whereError{start + 1, "invalid number " + src[i:j]}
+			}
+			k := j
+			for k < len(src) && unicode.IsLetter(rune(src[k])) {
+				k++
+			}
+			if unit := strings.ToUpper(src[j:k]); unit != "" {
+				mult, ok := sizeUnits[unit]
+				if !ok {
+					return nil, &whereError{j + 1, fmt.Sprintf("unknown unit %q (expected KB, MB, GB or TB)", src[j:k])}
+				}
+				n *= mult
+			}
+			tokens = append(tokens, exprToken{kind: "number", text: src[i:k], val: exprValue{n: n}, pos: start + 1})
+			i = k
+			continue
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: "ident", text: src[i:j], pos: start + 1})
+			i = j
+			continue
+		}
+		op := ""
+		for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")", "[", "]", ","} {
+			if strings.HasPrefix(src[i:], o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return nil, &whereError{start + 1, fmt.Sprintf("unexpected character %q", c)}
+		}
+		tokens = append(tokens, exprToken{kind: op, text: op, pos: start + 1})
+		i += len(op)
+	}
+	return append(tokens, exprToken{kind: "eof", text: "end of expression", pos: len(src) + 1}), nil
+}
+
+type whereParser struct {
+	tokens []exprToken
+	i      int
+	fields map[string]bool
+}
+
+func (p *whereParser) peek() exprToken { return p.tokens[p.i] }
+
+func (p *whereParser) next() exprToken {
+	tok := p.tokens[p.i]
+	if tok.kind != "eof" {
+		p.i++
+	}
+	return tok
+}
+
+func (p *whereParser) expect(kind string) error {
+	if tok := p.next(); tok.kind != kind {
+		return &whereError{tok.pos, fmt.Sprintf("expected %q, found %q", kind, tok.text)}
+	}
+	return nil
+}
+
+func (p *whereParser) parseOr() (*exprNode, error) {
+	return p.parseBinary("||", p.parseAnd, true)
+}
+
+func (p *whereParser) parseAnd() (*exprNode, error) {
+	return p.parseBinary("&&", p.parseComparison, false)
+}
+
+// parseBinary parses a chain of a short-circuit operator, which skips its right
+// operand when the left one evaluates to stop (true for ||, false for &&)
+func (p *whereParser) parseBinary(op string, operand func() (*exprNode, error), stop bool) (*exprNode, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == op {
+		tok := p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != typeBool || right.typ != typeBool {
+			return nil, &whereError{tok.pos, fmt.Sprintf("%s needs conditions on both sides", op)}
+		}
+		l, r := left.eval, right.eval
+		left = &exprNode{typ: typeBool, eval: func(repo RepoInfo) exprValue {
+			if a := l(repo).b; a == stop {
+				return exprValue{b: a}
+			}
+			return r(repo)
+		}}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseUnary() (*exprNode, error) {
+	if p.peek().kind != "!" {
+		return p.parsePrimary()
+	}
+	tok := p.next()
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if n.typ != typeBool {
+		return nil, &whereError{tok.pos, fmt.Sprintf("! needs a condition, found a %s (write !(a < b) to negate a comparison)", n.typ)}
+	}
+	eval := n.eval
+	return &exprNode{typ: typeBool, eval: func(r RepoInfo) exprValue { return exprValue{b: !eval(r).b} }}, nil
+}
+
+func (p *whereParser) parseComparison() (*exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	switch tok.kind {
+	case "==", "!=", "<", "<=", ">", ">=", "=~":
+	case "ident":
+		if tok.text != "in" {
+			return left, nil
+		}
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return compare(tok, left, right)
+}
+
+func (p *whereParser) parsePrimary() (*exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "(":
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	case "[":
+		var items []string
+		for p.peek().kind != "]" {
+			if len(items) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			item := p.next()
+			if item.kind != "string" {
+				return nil, &whereError{item.pos, fmt.Sprintf("lists hold strings, found %q", item.text)}
+			}
+			items = append(items, item.val.s)
+		}
+		p.next()
+		return literal(typeList, exprValue{l: items}), nil
+	case "string":
+		return literal(typeString, tok.val), nil
+	case "number":
+		return literal(typeNumber, tok.val), nil
+	case "ident":
+		switch tok.text {
+		case "true", "false":
+			return literal(typeBool, exprValue{b: tok.text == "true"}), nil
+		}
+		f, ok := whereFields[tok.text]
+		if !ok {
+			return nil, &whereError{tok.pos, fmt.Sprintf("unknown field %q (fields: %s)", tok.text, strings.Join(whereFieldNames(), ", "))}
+		}
+		p.fields[tok.text] = true
+		return &exprNode{typ: f.typ, eval: f.get}, nil
+	}
+	return nil, &whereError{tok.pos, fmt.Sprintf("expected a field or value, found %q", tok.text)}
+}
+
+func literal(typ exprType, v exprValue) *exprNode {
+	return &exprNode{typ: typ, eval: func(RepoInfo) exprValue { return v }, lit: &v}
+}
+
+func whereFieldNames() []string {
+	var names []string
+	for name := range whereFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compare type-checks one comparison and builds its node
+func compare(op exprToken, left, right *exprNode) (*exprNode, error) {
+	mismatch := func() error {
+		return &whereError{op.pos, fmt.Sprintf("cannot compare a %s with a %s using %s", left.typ, right.typ, op.text)}
+	}
+	// a string literal next to a date field is a date: "2024-01-31", "90d", ...
+	for _, pair := range [][2]*exprNode{{left, right}, {right, left}} {
+		if pair[0].typ == typeTime && pair[1].typ == typeString && pair[1].lit != nil {
+			t, err := parseDateFlag(pair[1].lit.s, time.Now())
+			if err != nil {
+				return nil, &whereError{op.pos, fmt.Sprintf("invalid date %q: %v", pair[1].lit.s, err)}
+			}
+			*pair[1] = *literal(typeTime, exprValue{t: t})
+		}
+	}
+	l, r := left.eval, right.eval
+	node := func(f func(a, b exprValue) bool) (*exprNode, error) {
+		return &exprNode{typ: typeBool, eval: func(repo RepoInfo) exprValue { return exprValue{b: f(l(repo), r(repo))} }}, nil
+	}
+	switch op.text {
+	case "in":
+		switch {
+		case left.typ == typeString && right.typ == typeList:
+			return node(func(a, b exprValue) bool {
+				for _, s := range b.l {
+					if strings.EqualFold(a.s, s) {
+						return true
+					}
+				}
+				return false
+			})
+		case left.typ == typeString && right.typ == typeString:
+			return node(func(a, b exprValue) bool { return strings.Contains(strings.ToLower(b.s), strings.ToLower(a.s)) })
+		}
+		return nil, &whereError{op.pos, fmt.Sprintf("in needs a string on the left and a list or string on the right, found %s in %s", left.typ, right.typ)}
+	case "=~":
+		if right.lit == nil || right.typ != typeString || left.typ != typeString && left.typ != typeList {
+			return nil, &whereError{op.pos, "=~ needs a string or list field on the left and a quoted regular expression on the right"}
+		}
+		re, err := regexp.Compile(right.lit.s)
+		if err != nil {
+			return nil, &whereError{op.pos, fmt.Sprintf("invalid regular expression: %v", err)}
+		}
+		return node(func(a, _ exprValue) bool {
+			if re.MatchString(a.s) {
+				return true
+			}
+			for _, s := range a.l {
+				if re.MatchString(s) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if left.typ != right.typ {
+		return nil, mismatch()
+	}
+	var cmp func(a, b exprValue) int
+	switch left.typ {
+	case typeNumber:
+		cmp = func(a, b exprValue) int {
+			switch {
+			case a.n < b.n:
+				return -1
+			case a.n > b.n:
+				return 1
+			}
+			return 0
+		}
+	case typeString:
+		cmp = func(a, b exprValue) int { return strings.Compare(strings.ToLower(a.s), strings.ToLower(b.s)) }
+	case typeTime:
+		cmp = func(a, b exprValue) int { return a.t.Compare(b.t) }
+	case typeBool:
+		if op.text != "==" && op.text != "!=" {
+			return nil, mismatch()
+		}
+		cmp = func(a, b exprValue) int {
+			if a.b == b.b {
+				return 0
+			}
+			return 1
+		}
+	default:
+		return nil, mismatch()
+	}
+	switch op.text {
+	case "==":
+		return node(func(a, b exprValue) bool { return cmp(a, b) == 0 })
+	case "!=":
+		return node(func(a, b exprValue) bool { return cmp(a, b) != 0 })
+	case "<":
+		return node(func(a, b exprValue) bool { return cmp(a, b) < 0 })
+	case "<=":
+		return node(func(a, b exprValue) bool { return cmp(a, b) <= 0 })
+	case ">":
+		return node(func(a, b exprValue) bool { return cmp(a, b) > 0 })
+	}
+	return node(func(a, b exprValue) bool { return cmp(a, b) >= 0 })
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWhereMatch(t *testing.T) {
+	now := time.Now()
+	repo := RepoInfo{
+		Name:       "api-server",
+		Owner:      "acme",
+		Visibility: "private",
+		Language:   "Go",
+		Topics:     []string{"security", "team-payments"},
+		Languages:  []LanguageShare{{Name: "Go"}, {Name: "Shell"}},
+		Stars:      12,
+		Size:       40000,
+		Pushed:     now.AddDate(-2, 0, 0),
+		Created:    time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// the example from the request
+		{`size < 50000 && !archived && language == "Go" && "security" in topics`, true},
+		{`size < 50000 && archived`, false},
+		{`!archived && !fork`, true},
+		{`!archived == true`, true}, // ! binds tighter than ==
+		{`!(stars > 10)`, false},
+		// unit suffixes
+		{`size < 50KB`, true},
+		{`size < 39kb`, false},
+		{`size >= 0.03MB`, true},
+		// dates: absolute and relative to now
+		{`pushed < "1y"`, true},
+		{`pushed > "90d"`, false},
+		{`created < "2021-01-01"`, true},
+		{`created >= "2020-01-02T00:00:00Z"`, true},
+		// in: list elements and substrings, case-insensitive
+		{`"Security" in topics`, true},
+		{`"compliance" in topics`, false},
+		{`language in ["rust", "go"]`, true},
+		{`"server" in name`, true},
+		{`"Shell" in languages`, true},
+		// =~ on strings and any element of a list
+		{`name =~ "^api-"`, true},
+		{`topics =~ "^team-"`, true},
+		{`topics =~ "^infra"`, false},
+		{`full_name == "ACME/api-server"`, true},
+		{`visibility != "public" || stars > 100`, true},
+		{`'a\b' == "a\\b"`, true}, // single quotes take the text as is
+	}
+	for _, tt := range tests {
+		w, err := parseWhere(tt.expr)
+		if err != nil {
+			t.Errorf("parseWhere(%s): %v", tt.expr, err)
+			continue
+		}
+		if got := w.match(repo); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestWhereShortCircuit(t *testing.T) {
+	calls := 0
+	whereFields["probe"] = struct {
+		typ exprType
+		get func(RepoInfo) exprValue
+	}{typeBool, func(RepoInfo) exprValue { calls++; return exprValue{b: true} }}
+	defer delete(whereFields, "probe")
+
+	tests := []struct {
+		expr      string
+		want      bool
+		wantCalls int
+	}{
+		{`archived && probe`, false, 0},
+		{`!archived || probe`, true, 0},
+		{`!archived && probe`, true, 1},
+		{`archived || probe`, true, 1},
+		{`archived && probe || probe`, true, 1},
+	}
+	for _, tt := range tests {
+		calls = 0
+		w, err := parseWhere(tt.expr)
+		if err != nil {
+			t.Errorf("parseWhere(%s): %v", tt.expr, err)
+			continue
+		}
+		if got := w.match(RepoInfo{}); got != tt.want || calls != tt.wantCalls {
+			t.Errorf("%s = %v with %d probe calls, want %v with %d", tt.expr, got, calls, tt.want, tt.wantCalls)
+		}
+	}
+}
+
+func TestWhereErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`size < 5XB`, `column 9: unknown unit "XB"`},
+		{`archived &&`, `column 12: expected a field or value, found "end of expression"`},
+		{`name`, `column 1: expression is a string`},
+		{`sise < 5`, `column 1: unknown field "sise"`},
+		{`size < "x"`, `column 6: cannot compare a number with a string using <`},
+		{`"a" in stars`, `column 5: in needs a string on the left`},
+		{`(archived`, `column 10: expected ")"`},
+		{`name == "x`, `column 9: unterminated string`},
+		{`!size < 5`, `column 1: ! needs a condition, found a number`},
+		{`pushed < "soon"`, `column 8: invalid date "soon"`},
+		{`name =~ "("`, `column 6: invalid regular expression`},
+		{`stars > 1 stars`, `column 11: unexpected "stars"`},
+		{`archived && size`, `column 10: && needs conditions on both sides`},
+		{`size # 5`, `column 6: unexpected character '#'`},
+	}
+	for _, tt := range tests {
+		_, err := parseWhere(tt.expr)
+		if err == nil {
+			t.Errorf("parseWhere(%s): expected an error", tt.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseWhere(%s) error = %q, want it to contain %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestWhereUses(t *testing.T) {
+	w, err := parseWhere(`language == "Go" || "Go" in languages`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.uses("language") || !w.uses("languages") || w.uses("topics") {
+		t.Errorf("uses reports %v, want language and languages only", w.fields)
+	}
+	var none *whereExpr
+	if none.uses("language") {
+		t.Error("a nil expression uses no fields")
+	}
+}